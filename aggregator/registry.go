@@ -0,0 +1,334 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+// Package aggregator keeps the state the agents report to the /api web
+// socket, so monitor-api can run as the monitor server of a small network.
+package aggregator
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/seeleteam/monitor-api/protocol"
+)
+
+const (
+	// DefaultOfflineTimeout is the time without emits after which a node is offline
+	DefaultOfflineTimeout = 60 * time.Second
+	// DefaultRetention is the time an offline node is kept after its last emit
+	DefaultRetention = 24 * time.Hour
+	// DefaultMaxNodes is the max nodes kept in the registry
+	DefaultMaxNodes = 10000
+)
+
+// ErrRegistryFull is returned for a new node when every node kept is online
+var ErrRegistryFull = errors.New("aggregator registry full")
+
+// Key identifies a node reported by the agents
+type Key struct {
+	ID         string `json:"id"`
+	Shard      uint   `json:"shard"`
+	NetVersion uint64 `json:"netVersion"`
+}
+
+// Node is the aggregated state of a node
+type Node struct {
+	Key
+	Info       *protocol.NodeInfo  `json:"info,omitempty"`
+	Stats      *protocol.NodeStats `json:"stats,omitempty"`
+	Block      *protocol.Block     `json:"block,omitempty"`
+	Latency    float64             `json:"latency"` // milliseconds, -1 means the ping failed
+	RemoteAddr string              `json:"remoteAddr"`
+	Connected  bool                `json:"connected"`
+	Online     bool                `json:"online"` // connected and emitted within the offline timeout
+	FirstSeen  time.Time           `json:"firstSeen"`
+	LastSeen   time.Time           `json:"lastSeen"`
+}
+
+// Shard is the summary of the nodes of a shard and net version
+type Shard struct {
+	Shard      uint   `json:"shard"`
+	NetVersion uint64 `json:"netVersion"`
+	Nodes      int    `json:"nodes"`
+	Online     int    `json:"online"`
+	BestHeight uint64 `json:"bestHeight"`
+	BestNode   string `json:"bestNode,omitempty"`
+}
+
+// Filter chooses the nodes of a query, the empty fields match all nodes
+type Filter struct {
+	ID         string
+	Shard      *uint
+	NetVersion *uint64
+	OnlineOnly bool
+}
+
+func (f *Filter) match(node *Node) bool {
+	switch {
+	case f.ID != "" && f.ID != node.ID:
+		return false
+	case f.Shard != nil && *f.Shard != node.Shard:
+		return false
+	case f.NetVersion != nil && *f.NetVersion != node.NetVersion:
+		return false
+	case f.OnlineOnly && !node.Online:
+		return false
+	}
+	return true
+}
+
+// Registry keeps the nodes reported by the agents in memory
+type Registry struct {
+	offlineTimeout time.Duration
+	retention      time.Duration
+	maxNodes       int
+	now            func() time.Time
+
+	mu    sync.Mutex
+	nodes map[Key]*Node
+}
+
+// NewRegistry create an empty registry
+func NewRegistry(options ...func(r *Registry)) *Registry {
+	r := &Registry{
+		offlineTimeout: DefaultOfflineTimeout,
+		retention:      DefaultRetention,
+		maxNodes:       DefaultMaxNodes,
+		now:            time.Now,
+		nodes:          make(map[Key]*Node),
+	}
+	for _, option := range options {
+		option(r)
+	}
+	return r
+}
+
+// WithOfflineTimeout sets the time without emits after which a node is offline
+func WithOfflineTimeout(timeout time.Duration) func(r *Registry) {
+	return func(r *Registry) {
+		if timeout > 0 {
+			r.offlineTimeout = timeout
+		}
+	}
+}
+
+// WithRetention sets the time an offline node is kept after its last emit
+func WithRetention(retention time.Duration) func(r *Registry) {
+	return func(r *Registry) {
+		if retention > 0 {
+			r.retention = retention
+		}
+	}
+}
+
+// WithMaxNodes sets the max nodes kept, the offline node seen least recently
+// is dropped for a new node when the registry is full
+func WithMaxNodes(maxNodes int) func(r *Registry) {
+	return func(r *Registry) {
+		if maxNodes > 0 {
+			r.maxNodes = maxNodes
+		}
+	}
+}
+
+// Handle records the emit of the agent at the remote address and returns the
+// key of the node. Emits other than hello, stats, block, latency and
+// node-ping are ignored with an empty key.
+func (r *Registry) Handle(remoteAddr string, msg *protocol.Message) (Key, error) {
+	switch msg.Event {
+	case protocol.EventHello, protocol.EventStats, protocol.EventBlock, protocol.EventLatency, protocol.EventNodePing:
+	default:
+		return Key{}, nil
+	}
+	payload, err := msg.DecodePayload()
+	if err != nil {
+		return Key{}, err
+	}
+
+	var (
+		header  protocol.Header
+		info    *protocol.NodeInfo
+		stats   *protocol.NodeStats
+		block   *protocol.Block
+		latency *float64
+	)
+	switch p := payload.(type) {
+	case *protocol.Hello:
+		header, info, stats, block = p.Header, p.Info, p.Stats, p.Block
+		if latency, err = parseLatency(p.Latency); err != nil {
+			return Key{}, fmt.Errorf("invalid hello of %v: %v", p.ID, err)
+		}
+	case *protocol.StatsReport:
+		header, stats = p.Header, p.Stats
+	case *protocol.BlockReport:
+		header, block = p.Header, p.Block
+	case *protocol.LatencyReport:
+		header = p.Header
+		if latency, err = parseLatency(p.Latency); err != nil {
+			return Key{}, fmt.Errorf("invalid latency of %v: %v", p.ID, err)
+		}
+	case *protocol.Ping:
+		header = p.Header
+	}
+	key := Key{ID: header.ID, Shard: header.Shard, NetVersion: header.NetVersion}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := r.now()
+	node, ok := r.nodes[key]
+	if !ok {
+		if err = r.makeRoom(now); err != nil {
+			return Key{}, fmt.Errorf("node %v: %w", key.ID, err)
+		}
+		node = &Node{Key: key, FirstSeen: now}
+		r.nodes[key] = node
+	}
+	node.RemoteAddr = remoteAddr
+	node.Connected = true
+	node.LastSeen = now
+	if info != nil {
+		node.Info = info
+	}
+	if stats != nil {
+		node.Stats = stats
+	}
+	if block != nil {
+		node.Block = block
+	}
+	if latency != nil {
+		node.Latency = *latency
+	}
+	return key, nil
+}
+
+// parseLatency parses the latency in milliseconds, nil if empty
+func parseLatency(latency string) (*float64, error) {
+	if latency == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(latency, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid latency %q", latency)
+	}
+	return &value, nil
+}
+
+// Disconnect marks the nodes of the closed connection offline
+func (r *Registry) Disconnect(remoteAddr string, keys ...Key) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, key := range keys {
+		if node, ok := r.nodes[key]; ok && node.RemoteAddr == remoteAddr {
+			node.Connected = false
+		}
+	}
+}
+
+// online reports whether the node is connected and emitted within the offline timeout
+func (r *Registry) online(node *Node, now time.Time) bool {
+	return node.Connected && now.Sub(node.LastSeen) <= r.offlineTimeout
+}
+
+// expire drops the offline nodes not seen within the retention, r.mu must be held
+func (r *Registry) expire(now time.Time) {
+	for key, node := range r.nodes {
+		if !r.online(node, now) && now.Sub(node.LastSeen) > r.retention {
+			delete(r.nodes, key)
+		}
+	}
+}
+
+// makeRoom makes room for a new node, if the registry is full it drops the
+// offline node seen least recently, or returns ErrRegistryFull if every node
+// is online. r.mu must be held.
+func (r *Registry) makeRoom(now time.Time) error {
+	r.expire(now)
+	if len(r.nodes) < r.maxNodes {
+		return nil
+	}
+	var oldest *Node
+	for _, node := range r.nodes {
+		if !r.online(node, now) && (oldest == nil || node.LastSeen.Before(oldest.LastSeen)) {
+			oldest = node
+		}
+	}
+	if oldest == nil {
+		return ErrRegistryFull
+	}
+	delete(r.nodes, oldest.Key)
+	return nil
+}
+
+// snapshot returns a copy of the node with its online state
+func (r *Registry) snapshot(node *Node, now time.Time) Node {
+	n := *node
+	n.Online = r.online(node, now)
+	return n
+}
+
+// Nodes returns the nodes of the filter ordered by shard, net version and id
+func (r *Registry) Nodes(filter Filter) []Node {
+	r.mu.Lock()
+	now := r.now()
+	r.expire(now)
+	nodes := make([]Node, 0, len(r.nodes))
+	for _, node := range r.nodes {
+		n := r.snapshot(node, now)
+		if filter.match(&n) {
+			nodes = append(nodes, n)
+		}
+	}
+	r.mu.Unlock()
+
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Key.less(nodes[j].Key) })
+	return nodes
+}
+
+func (k Key) less(other Key) bool {
+	if k.Shard != other.Shard {
+		return k.Shard < other.Shard
+	}
+	if k.NetVersion != other.NetVersion {
+		return k.NetVersion < other.NetVersion
+	}
+	return k.ID < other.ID
+}
+
+// ErrNodeNotFound is returned when no agent reported the node
+var ErrNodeNotFound = errors.New("node not found")
+
+// Node returns the nodes reported with the id, one per shard and net version
+func (r *Registry) Node(id string) ([]Node, error) {
+	nodes := r.Nodes(Filter{ID: id})
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("node %v: %w", id, ErrNodeNotFound)
+	}
+	return nodes, nil
+}
+
+// Shards returns the summary of every shard and net version
+func (r *Registry) Shards() []Shard {
+	shards := make([]Shard, 0)
+	for _, node := range r.Nodes(Filter{}) {
+		if len(shards) == 0 || shards[len(shards)-1].Shard != node.Shard || shards[len(shards)-1].NetVersion != node.NetVersion {
+			shards = append(shards, Shard{Shard: node.Shard, NetVersion: node.NetVersion})
+		}
+		shard := &shards[len(shards)-1]
+		shard.Nodes++
+		if !node.Online {
+			continue
+		}
+		shard.Online++
+		if node.Block != nil && (shard.BestNode == "" || node.Block.Height > shard.BestHeight) {
+			shard.BestHeight = node.Block.Height
+			shard.BestNode = node.ID
+		}
+	}
+	return shards
+}
@@ -0,0 +1,127 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package aggregator
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/seeleteam/monitor-api/protocol"
+)
+
+// parse decodes the emit like the /api web socket handler
+func parse(t *testing.T, data string) *protocol.Message {
+	msg, err := protocol.Parse([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestRegistry(t *testing.T) {
+	now := time.Unix(1000, 0)
+	r := NewRegistry(WithOfflineTimeout(30 * time.Second))
+	r.now = func() time.Time { return now }
+
+	key, err := r.Handle("agent1", parse(t, `{"emit":["hello",{"id":"node1","shard":1,"netVersion":1,"secret":"s",
+		"info":{"name":"node1","netVersion":1},"block":{"headHash":"0x1","height":10},"stats":{"active":true},"latency":"1.5"}]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, Key{ID: "node1", Shard: 1, NetVersion: 1}, key)
+	_, err = r.Handle("agent2", parse(t, `{"emit":["block",{"id":"node2","shard":1,"netVersion":1,"block":{"headHash":"0x2","height":12}}]}`))
+	assert.NoError(t, err)
+	_, err = r.Handle("agent3", parse(t, `{"emit":["stats",{"id":"node3","shard":2,"netVersion":1,"stats":{"active":false}}]}`))
+	assert.NoError(t, err)
+
+	// ignored and invalid emits
+	key, err = r.Handle("agent1", parse(t, `{"emit":["history",{"id":"node1"}]}`))
+	assert.NoError(t, err)
+	assert.Equal(t, Key{}, key)
+	_, err = r.Handle("agent1", parse(t, `{"emit":["stats",{"shard":1,"netVersion":1,"stats":{}}]}`))
+	assert.EqualError(t, err, "protocol: invalid stats payload: missing id")
+	_, err = r.Handle("agent1", parse(t, `{"emit":["latency",{"id":"node1","shard":1,"netVersion":1,"latency":"fast"}]}`))
+	assert.EqualError(t, err, `invalid latency of node1: invalid latency "fast"`)
+
+	nodes := r.Nodes(Filter{})
+	assert.Len(t, nodes, 3)
+	assert.Equal(t, "node1", nodes[0].ID)
+	assert.Equal(t, 1.5, nodes[0].Latency)
+	assert.Equal(t, "node1", nodes[0].Info.Name)
+	assert.True(t, nodes[0].Online)
+
+	shard := uint(2)
+	nodes = r.Nodes(Filter{Shard: &shard})
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, "node3", nodes[0].ID)
+
+	assert.Equal(t, []Shard{
+		{Shard: 1, NetVersion: 1, Nodes: 2, Online: 2, BestHeight: 12, BestNode: "node2"},
+		{Shard: 2, NetVersion: 1, Nodes: 1, Online: 1},
+	}, r.Shards())
+
+	// node2 disconnects, node3 stops emitting
+	r.Disconnect("agent2", Key{ID: "node2", Shard: 1, NetVersion: 1})
+	now = now.Add(20 * time.Second)
+	_, err = r.Handle("agent1", parse(t, `{"emit":["latency",{"id":"node1","shard":1,"netVersion":1,"latency":"2"}]}`))
+	assert.NoError(t, err)
+	now = now.Add(20 * time.Second)
+
+	nodes = r.Nodes(Filter{OnlineOnly: true})
+	assert.Len(t, nodes, 1)
+	assert.Equal(t, "node1", nodes[0].ID)
+	assert.Equal(t, 2.0, nodes[0].Latency)
+	assert.Equal(t, uint64(10), nodes[0].Block.Height)
+	assert.Equal(t, []Shard{
+		{Shard: 1, NetVersion: 1, Nodes: 2, Online: 1, BestHeight: 10, BestNode: "node1"},
+		{Shard: 2, NetVersion: 1, Nodes: 1},
+	}, r.Shards())
+
+	_, err = r.Node("node4")
+	assert.EqualError(t, err, "node node4: node not found")
+	nodes, err = r.Node("node2")
+	assert.NoError(t, err)
+	assert.False(t, nodes[0].Online)
+}
+
+func TestRegistryEviction(t *testing.T) {
+	now := time.Unix(1000, 0)
+	r := NewRegistry(WithOfflineTimeout(30*time.Second), WithRetention(time.Hour), WithMaxNodes(2))
+	r.now = func() time.Time { return now }
+	stats := func(id string) *protocol.Message {
+		return parse(t, `{"emit":["stats",{"id":"`+id+`","shard":1,"netVersion":1,"stats":{"active":true}}]}`)
+	}
+
+	_, err := r.Handle("agent1", stats("node1"))
+	assert.NoError(t, err)
+	now = now.Add(time.Second)
+	_, err = r.Handle("agent2", stats("node2"))
+	assert.NoError(t, err)
+
+	// every node online, no room for a new node
+	_, err = r.Handle("agent3", stats("node3"))
+	assert.True(t, errors.Is(err, ErrRegistryFull))
+	assert.EqualError(t, err, "node node3: aggregator registry full")
+
+	// the offline node seen least recently makes room
+	r.Disconnect("agent2", Key{ID: "node2", Shard: 1, NetVersion: 1})
+	r.Disconnect("agent1", Key{ID: "node1", Shard: 1, NetVersion: 1})
+	_, err = r.Handle("agent3", stats("node3"))
+	assert.NoError(t, err)
+	_, err = r.Node("node1")
+	assert.True(t, errors.Is(err, ErrNodeNotFound))
+	assert.Len(t, r.Nodes(Filter{}), 2)
+
+	// the offline nodes are dropped after the retention
+	now = now.Add(time.Hour + 2*time.Second)
+	_, err = r.Handle("agent3", stats("node3"))
+	assert.NoError(t, err)
+	nodes := r.Nodes(Filter{})
+	if assert.Len(t, nodes, 1) {
+		assert.Equal(t, "node3", nodes[0].ID)
+	}
+}
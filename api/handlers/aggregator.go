@@ -0,0 +1,74 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/seeleteam/monitor-api/aggregator"
+)
+
+// error codes of the aggregator api
+const (
+	codeInvalidFilter = "invalid_filter"
+)
+
+// AggregatorNodes answers the nodes reported by the agents, filtered by
+// ?shard=, ?netVersion= and ?online=true
+func AggregatorNodes(registry *aggregator.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		var filter aggregator.Filter
+		if shard := c.Query("shard"); shard != "" {
+			value, err := strconv.ParseUint(shard, 10, 32)
+			if err != nil {
+				abortWithError(c, http.StatusBadRequest, codeInvalidFilter, fmt.Errorf("invalid shard %q", shard))
+				return
+			}
+			s := uint(value)
+			filter.Shard = &s
+		}
+		if netVersion := c.Query("netVersion"); netVersion != "" {
+			value, err := strconv.ParseUint(netVersion, 10, 64)
+			if err != nil {
+				abortWithError(c, http.StatusBadRequest, codeInvalidFilter, fmt.Errorf("invalid netVersion %q", netVersion))
+				return
+			}
+			filter.NetVersion = &value
+		}
+		if online := c.Query("online"); online != "" {
+			value, err := strconv.ParseBool(online)
+			if err != nil {
+				abortWithError(c, http.StatusBadRequest, codeInvalidFilter, fmt.Errorf("invalid online %q", online))
+				return
+			}
+			filter.OnlineOnly = value
+		}
+		c.JSON(http.StatusOK, H{"data": registry.Nodes(filter)})
+	}
+}
+
+// AggregatorNode answers the node :id, once per shard and net version it was reported in
+func AggregatorNode(registry *aggregator.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		nodes, err := registry.Node(c.Param("id"))
+		if err != nil {
+			abortWithError(c, http.StatusNotFound, codeNodeNotFound, err)
+			return
+		}
+		c.JSON(http.StatusOK, H{"node": c.Param("id"), "data": nodes})
+	}
+}
+
+// AggregatorShards answers the summary of the nodes of every shard and net version
+func AggregatorShards(registry *aggregator.Registry) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, H{"data": registry.Shards()})
+	}
+}
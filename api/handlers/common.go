@@ -10,3 +10,14 @@ import (
 )
 
 type H gin.H
+
+// Error is the error envelope of the api, {"error": {"code": ..., "message": ...}}
+type Error struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// abortWithError answers the error envelope with the http status
+func abortWithError(c *gin.Context, status int, code string, err error) {
+	c.AbortWithStatusJSON(status, H{"error": Error{Code: code, Message: err.Error()}})
+}
@@ -0,0 +1,95 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package handlers
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+
+	"github.com/seeleteam/monitor-api/ws"
+)
+
+// eventsKeepAlive is the interval of the comment lines keeping idle streams open
+var eventsKeepAlive = 15 * time.Second
+
+// responseWriterKey is the context key of the http.ResponseWriter of the request
+type responseWriterKey struct{}
+
+// WithResponseWriter keeps the http.ResponseWriter of the request in its
+// context, gin hides it from the handlers, so the event streams can clear the
+// WriteTimeout of the server
+func WithResponseWriter(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), responseWriterKey{}, w)))
+	})
+}
+
+// clearWriteDeadline lets the stream outlive the WriteTimeout of the server
+func clearWriteDeadline(c *gin.Context) {
+	if w, ok := c.Request.Context().Value(responseWriterKey{}).(http.ResponseWriter); ok {
+		http.NewResponseController(w).SetWriteDeadline(time.Time{})
+	}
+}
+
+// Events streams the events of the monitored nodes as server-sent events,
+// ?node= keeps the events of one node, ?type=stats,block keeps the types.
+func Events() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		node := c.Query("node")
+		if node != "" {
+			if _, err := ws.Lookup(node); err != nil {
+				abortWithError(c, http.StatusNotFound, codeNodeNotFound, err)
+				return
+			}
+		}
+		var types []string
+		for _, value := range c.QueryArray("type") {
+			for _, t := range strings.Split(value, ",") {
+				if t = strings.TrimSpace(t); t != "" {
+					types = append(types, t)
+				}
+			}
+		}
+
+		events, unsubscribe := ws.Subscribe(node, types...)
+		defer unsubscribe()
+		keepAlive := time.NewTicker(eventsKeepAlive)
+		defer keepAlive.Stop()
+
+		clearWriteDeadline(c)
+		c.Header("Content-Type", "text/event-stream")
+		c.Header("Cache-Control", "no-cache")
+		c.Header("Connection", "keep-alive")
+		c.Header("X-Accel-Buffering", "no")
+
+		done := c.Request.Context().Done()
+		headerSent := false
+		// the stream ends once a write fails or the request is done
+		c.Stream(func(w io.Writer) bool {
+			if !headerSent {
+				// flushed by Stream, the client sees the stream open before the first event
+				headerSent = true
+				c.Writer.WriteHeaderNow()
+				return true
+			}
+			select {
+			case event := <-events:
+				return sse.Encode(w, sse.Event{Event: event.Type, Data: event}) == nil
+			case <-keepAlive.C:
+				_, err := io.WriteString(w, ": keep-alive\n\n")
+				return err == nil
+			case <-done:
+				return false
+			}
+		})
+	}
+}
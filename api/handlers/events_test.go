@@ -0,0 +1,55 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package handlers
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEventsOutliveWriteTimeout(t *testing.T) {
+	keepAlive := eventsKeepAlive
+	eventsKeepAlive = 200 * time.Millisecond
+	defer func() { eventsKeepAlive = keepAlive }()
+
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/v1/events", Events())
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &http.Server{Handler: WithResponseWriter(e), WriteTimeout: 100 * time.Millisecond}
+	go server.Serve(listener)
+	defer server.Close()
+
+	resp, err := http.Get("http://" + listener.Addr().String() + "/v1/events")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	// the keep-alives are written long after the WriteTimeout
+	reader := bufio.NewReader(resp.Body)
+	for keepAlives := 0; keepAlives < 3; {
+		line, err := reader.ReadString('\n')
+		if !assert.NoError(t, err) {
+			return
+		}
+		if strings.HasPrefix(line, ": keep-alive") {
+			keepAlives++
+		}
+	}
+}
@@ -0,0 +1,72 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/seeleteam/monitor-api/config"
+	"github.com/seeleteam/monitor-api/ws"
+)
+
+var startTime = time.Now()
+
+// nodeReadiness is the status of a node with its readiness
+type nodeReadiness struct {
+	ws.Status
+	Ready  bool   `json:"ready"`
+	Reason string `json:"reason,omitempty"`
+}
+
+// Healthz answers while the process is alive
+func Healthz() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, H{
+			"status": "ok",
+			"uptime": time.Since(startTime).Truncate(time.Second).String(),
+		})
+	}
+}
+
+// Readyz answers 200 if every monitored node is reachable, connected to the
+// monitor server and reported recently, otherwise 503 with the reasons
+func Readyz() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		periods := 0
+		if currentWebSocketConfig := config.WebSocketSettings(); currentWebSocketConfig != nil {
+			periods = currentWebSocketConfig.ReadyReportPeriods
+		}
+
+		now := time.Now()
+		statuses := ws.Statuses()
+		ready := len(statuses) > 0
+		nodes := make([]nodeReadiness, 0, len(statuses))
+		started := make(map[string]bool, len(statuses))
+		for _, status := range statuses {
+			nodeReady, reason := status.Ready(periods, now)
+			ready = ready && nodeReady
+			started[status.Name] = true
+			nodes = append(nodes, nodeReadiness{Status: status, Ready: nodeReady, Reason: reason})
+		}
+		// the services are registered once started, a node still starting or
+		// failed to start is not ready
+		for _, node := range config.SeeleConfig.ServerConfig.GetNodeConfigs() {
+			if !started[node.Name] {
+				ready = false
+				nodes = append(nodes, nodeReadiness{Status: ws.Status{Name: node.Name}, Reason: "not started"})
+			}
+		}
+
+		if !ready {
+			c.JSON(http.StatusServiceUnavailable, H{"status": "unavailable", "nodes": nodes})
+			return
+		}
+		c.JSON(http.StatusOK, H{"status": "ok", "nodes": nodes})
+	}
+}
@@ -0,0 +1,111 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/seeleteam/monitor-api/rpc"
+	"github.com/seeleteam/monitor-api/ws"
+)
+
+// error codes of the node api
+const (
+	codeNodeNotFound  = "node_not_found"
+	codeBlockNotFound = "block_not_found"
+	codeInvalidHeight = "invalid_height"
+	codeRPCError      = "rpc_error"
+)
+
+// lookupService returns the service of the ?node= query, it answers the
+// error if the node is not monitored
+func lookupService(c *gin.Context) (*ws.Service, bool) {
+	service, err := ws.Lookup(c.Query("node"))
+	if err != nil {
+		abortWithError(c, http.StatusNotFound, codeNodeNotFound, err)
+		return nil, false
+	}
+	return service, true
+}
+
+// answer answers the data of the node, or the error envelope of the rpc error
+func answer(c *gin.Context, service *ws.Service, data interface{}, err error) {
+	if err != nil {
+		if errors.Is(err, rpc.ErrBlockNotFound) {
+			abortWithError(c, http.StatusNotFound, codeBlockNotFound, err)
+			return
+		}
+		abortWithError(c, http.StatusBadGateway, codeRPCError, err)
+		return
+	}
+	c.JSON(http.StatusOK, H{"node": service.Name(), "data": data})
+}
+
+// NodeInfo answers the info of the monitored node
+func NodeInfo() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service, ok := lookupService(c)
+		if !ok {
+			return
+		}
+		info, err := service.NodeInfo(c.Request.Context())
+		answer(c, service, info, err)
+	}
+}
+
+// NodeStats answers the stats of the monitored node
+func NodeStats() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service, ok := lookupService(c)
+		if !ok {
+			return
+		}
+		stats, err := service.NodeStats(c.Request.Context())
+		answer(c, service, stats, err)
+	}
+}
+
+// LatestBlock answers the current block of the monitored node
+func LatestBlock() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		service, ok := lookupService(c)
+		if !ok {
+			return
+		}
+		block, err := service.LatestBlock(c.Request.Context())
+		answer(c, service, block, err)
+	}
+}
+
+// BlockByHeight answers the block at the :height of the monitored node, the
+// router has no static route next to :height so it answers latest too
+func BlockByHeight() gin.HandlerFunc {
+	latestBlock := LatestBlock()
+	return func(c *gin.Context) {
+		if c.Param("height") == "latest" {
+			latestBlock(c)
+			return
+		}
+		// the node reads a negative height as the latest block
+		height, err := strconv.ParseUint(c.Param("height"), 10, 64)
+		if err != nil || height > math.MaxInt64 {
+			abortWithError(c, http.StatusBadRequest, codeInvalidHeight, fmt.Errorf("invalid height %q", c.Param("height")))
+			return
+		}
+		service, ok := lookupService(c)
+		if !ok {
+			return
+		}
+		block, err := service.Block(c.Request.Context(), height)
+		answer(c, service, block, err)
+	}
+}
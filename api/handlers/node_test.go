@@ -0,0 +1,46 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNodeErrorEnvelope(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	e.GET("/v1/node/info", NodeInfo())
+	e.GET("/v1/block/:height", BlockByHeight())
+
+	for path, expected := range map[string]struct {
+		status int
+		code   string
+	}{
+		"/v1/node/info?node=unknown":     {http.StatusNotFound, codeNodeNotFound},
+		"/v1/block/latest?node=unknown":  {http.StatusNotFound, codeNodeNotFound},
+		"/v1/block/abc":                  {http.StatusBadRequest, codeInvalidHeight},
+		"/v1/block/-1":                   {http.StatusBadRequest, codeInvalidHeight},
+		"/v1/block/9223372036854775808":  {http.StatusBadRequest, codeInvalidHeight},
+		"/v1/block/18446744073709551615": {http.StatusBadRequest, codeInvalidHeight},
+	} {
+		recorder := httptest.NewRecorder()
+		e.ServeHTTP(recorder, httptest.NewRequest("GET", path, nil))
+		assert.Equal(t, expected.status, recorder.Code, path)
+
+		var body struct {
+			Error Error `json:"error"`
+		}
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &body), path)
+		assert.Equal(t, expected.code, body.Error.Code, path)
+		assert.NotEmpty(t, body.Error.Message, path)
+	}
+}
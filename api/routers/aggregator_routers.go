@@ -0,0 +1,23 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/seeleteam/monitor-api/aggregator"
+	"github.com/seeleteam/monitor-api/api/handlers"
+)
+
+// InitAggregatorRouters init the api of the nodes reported to the /api web socket
+func InitAggregatorRouters(e *gin.Engine, registry *aggregator.Registry) {
+	v1 := e.Group("/v1/aggregator")
+	{
+		v1.GET("/nodes", handlers.AggregatorNodes(registry))
+		v1.GET("/nodes/:id", handlers.AggregatorNode(registry))
+		v1.GET("/shards", handlers.AggregatorShards(registry))
+	}
+}
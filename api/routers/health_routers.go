@@ -0,0 +1,18 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/seeleteam/monitor-api/api/handlers"
+)
+
+// InitHealthRouters init the liveness and readiness api
+func InitHealthRouters(e *gin.Engine) {
+	e.GET("/healthz", handlers.Healthz())
+	e.GET("/readyz", handlers.Readyz())
+}
@@ -0,0 +1,17 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/seeleteam/monitor-api/core/metrics"
+)
+
+// InitMetricsRouters init the metrics api in the Prometheus text format
+func InitMetricsRouters(e *gin.Engine) {
+	e.GET("/metrics", gin.WrapH(metrics.Handler()))
+}
@@ -6,16 +6,51 @@
 package routers
 
 import (
+	"time"
+
 	"github.com/gin-gonic/gin"
 
+	"github.com/seeleteam/monitor-api/aggregator"
 	"github.com/seeleteam/monitor-api/config"
 )
 
 // InitRouters init routers
 func InitRouters(e *gin.Engine) {
+	// health check
+	InitHealthRouters(e)
+
+	// aggregator, keep the emits of the agents connected to /api
+	var registry *aggregator.Registry
+	enableAggregator := config.SeeleConfig.ServerConfig.EnableAggregator
+	if enableAggregator {
+		var offlineTimeout, retention time.Duration
+		var maxNodes int
+		if currentAggregatorConfig := config.SeeleConfig.ServerConfig.AggregatorConfig; currentAggregatorConfig != nil {
+			offlineTimeout = currentAggregatorConfig.OfflineTimeout
+			retention = currentAggregatorConfig.Retention
+			maxNodes = currentAggregatorConfig.MaxNodes
+		}
+		registry = aggregator.NewRegistry(aggregator.WithOfflineTimeout(offlineTimeout),
+			aggregator.WithRetention(retention),
+			aggregator.WithMaxNodes(maxNodes))
+		InitAggregatorRouters(e, registry)
+	}
+
 	//web socket
 	enableWs := config.SeeleConfig.ServerConfig.EnableWebSocket
-	if enableWs {
-		InitWsRouters(e)
+	if enableWs || enableAggregator {
+		InitWsRouters(e, registry)
+	}
+
+	// node data api, served by the ws services of the monitored nodes
+	enableRPC := config.SeeleConfig.ServerConfig.EnableRPC
+	if enableRPC {
+		InitV1Routers(e)
+	}
+
+	//metrics
+	enableMetrics := config.SeeleConfig.ServerConfig.EnableMetrics
+	if enableMetrics {
+		InitMetricsRouters(e)
 	}
 }
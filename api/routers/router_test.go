@@ -0,0 +1,76 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package routers
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/seeleteam/monitor-api/aggregator"
+	"github.com/seeleteam/monitor-api/config"
+	"github.com/seeleteam/monitor-api/core/logs"
+	"github.com/seeleteam/monitor-api/protocol"
+)
+
+// the routes of the api must not conflict in the router
+func TestInitRoutersNoConflict(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	assert.NotPanics(t, func() {
+		InitHealthRouters(e)
+		InitAggregatorRouters(e, aggregator.NewRegistry())
+		InitWsRouters(e, nil)
+		InitV1Routers(e)
+		InitMetricsRouters(e)
+	})
+}
+
+// without registry node-ping is answered and the other emits are echoed back
+func TestWsHandlerEcho(t *testing.T) {
+	if logs.GetLogger() == nil {
+		config.SeeleConfig = &config.Config{ServerConfig: &config.ServerConfig{
+			LogLevel:     logrus.PanicLevel,
+			EngineConfig: &config.EngineConfig{},
+		}}
+		logs.NewLogger()
+	}
+	gin.SetMode(gin.TestMode)
+	e := gin.New()
+	InitWsRouters(e, nil)
+	server := httptest.NewServer(e)
+	defer server.Close()
+
+	conn, _, err := websocket.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/api", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	latency := `{"emit":["latency",{"id":"node1","latency":"1"}]}`
+	for _, emit := range []string{
+		latency,
+		`not json`,
+		`{"emit":["node-ping",{"id":"node1","clientTime":"1"}]}`,
+	} {
+		assert.NoError(t, conn.WriteMessage(websocket.TextMessage, []byte(emit)))
+	}
+
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	_, data, err := conn.ReadMessage()
+	assert.NoError(t, err)
+	assert.Equal(t, latency, string(data))
+	_, data, err = conn.ReadMessage()
+	assert.NoError(t, err)
+	msg, err := protocol.Parse(data)
+	assert.NoError(t, err)
+	assert.Equal(t, protocol.EventNodePong, msg.Event)
+}
@@ -0,0 +1,25 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package routers
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/seeleteam/monitor-api/api/handlers"
+)
+
+// InitV1Routers init the v1 api of the monitored node data, ?node= chooses
+// the node when several nodes are monitored
+func InitV1Routers(e *gin.Engine) {
+	v1 := e.Group("/v1")
+	{
+		v1.GET("/node/info", handlers.NodeInfo())
+		v1.GET("/node/stats", handlers.NodeStats())
+		// also /block/latest, a static route conflicts with :height
+		v1.GET("/block/:height", handlers.BlockByHeight())
+		v1.GET("/events", handlers.Events())
+	}
+}
@@ -6,7 +6,6 @@
 package routers
 
 import (
-	"encoding/json"
 	"net/http"
 	"os"
 	"time"
@@ -14,19 +13,23 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 
+	"github.com/seeleteam/monitor-api/aggregator"
+	"github.com/seeleteam/monitor-api/config"
 	"github.com/seeleteam/monitor-api/core/logs"
 	"github.com/seeleteam/monitor-api/core/utils"
+	"github.com/seeleteam/monitor-api/protocol"
 )
 
-// InitWsRouters init the web socket api
-func InitWsRouters(e *gin.Engine) {
-	e.GET("/api", bindWsHandler())
+// InitWsRouters init the web socket api, the emits of the agents are kept in
+// the registry if not nil
+func InitWsRouters(e *gin.Engine, registry *aggregator.Registry) {
+	e.GET("/api", bindWsHandler(registry))
 }
 
 // bindWsHandler bind the handler for web socket
-func bindWsHandler() gin.HandlerFunc {
+func bindWsHandler(registry *aggregator.Registry) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		wsHandler(c.Writer, c.Request)
+		wsHandler(c.Writer, c.Request, registry)
 	}
 }
 
@@ -38,13 +41,38 @@ var upGrader = websocket.Upgrader{
 	HandshakeTimeout: time.Duration(time.Second * 60),
 }
 
-// wsHandler web socket handler
-func wsHandler(w http.ResponseWriter, r *http.Request) {
+// maxSecretSkew is the max clock difference accepted for a signed hello
+const maxSecretSkew = 5 * time.Minute
+
+// wsHandler web socket handler, without registry the emits are echoed back
+func wsHandler(w http.ResponseWriter, r *http.Request, registry *aggregator.Registry) {
 	conn, err := upGrader.Upgrade(w, r, nil)
 	if err != nil {
 		logs.Error("Failed to set websocket upgrade: %+v", err)
 		return
 	}
+	defer conn.Close()
+
+	// the nodes reported on this connection are offline once it is closed
+	remoteAddr := conn.RemoteAddr().String()
+	reported := make(map[aggregator.Key]struct{})
+	if registry != nil {
+		defer func() {
+			keys := make([]aggregator.Key, 0, len(reported))
+			for key := range reported {
+				keys = append(keys, key)
+			}
+			registry.Disconnect(remoteAddr, keys...)
+		}()
+	}
+
+	// if WsPass is set, the agent must send a signed hello before anything but node-ping
+	wsPass := ""
+	if currentWebSocketConfig := config.WebSocketSettings(); currentWebSocketConfig != nil {
+		wsPass = currentWebSocketConfig.WsPass
+	}
+	authorized := wsPass == ""
+
 	for {
 		msgType, msgData, err := conn.ReadMessage()
 		switch err.(type) {
@@ -62,36 +90,75 @@ func wsHandler(w http.ResponseWriter, r *http.Request) {
 			continue
 		}
 
-		var msg map[string][]interface{}
-		err = json.Unmarshal(msgData, &msg)
+		msg, err := protocol.Parse(msgData)
 		if err != nil {
-			logs.Error("WsHandler receive msg %v", string(msgData))
+			logs.Error("Invalid stats server message %v, err %v", string(msgData), err)
+			continue
 		}
+		command := msg.Event
+		logs.Debug("receive msg %v, payload is %s\n", command, msg.Payload)
 
-		resultData := make(map[string][]interface{})
-
-		command, ok := msg["emit"][0].(string)
-		if !ok {
-			logs.Error("Invalid stats server message type", "type", msg["emit"][0])
+		if !authorized && command == protocol.EventHello {
+			if !verifyHello(wsPass, msg) {
+				logs.Warn("reject agent %v, invalid hello secret", conn.RemoteAddr())
+				closeWithCode(conn, websocket.ClosePolicyViolation, "invalid secret")
+				return
+			}
+			authorized = true
+		}
+		if !authorized && command != protocol.EventNodePing {
+			logs.Warn("reject agent %v, %v before authorized hello", conn.RemoteAddr(), command)
+			closeWithCode(conn, websocket.ClosePolicyViolation, "unauthorized")
 			return
 		}
-		logs.Debug("receive msg len is %v, msg is %+v\n", len(msg["emit"]), utils.StructSerialize(msg))
-		if len(msg["emit"]) == 2 && command == "node-ping" {
+
+		if registry != nil && authorized {
+			key, err := registry.Handle(remoteAddr, msg)
+			if err != nil {
+				logs.Warn("agent %v: %v", remoteAddr, err)
+			} else if key.ID != "" {
+				reported[key] = struct{}{}
+			}
+		}
+
+		if command == protocol.EventNodePing {
 			hostname, _ := os.Hostname()
-			resultData = map[string][]interface{}{
-				"emit": {"node-pong", map[string]string{
-					"id":         hostname + "_" + conn.LocalAddr().String(),
-					"clientTime": time.Now().String(),
-				}},
+			responseData, err := protocol.Encode(protocol.EventNodePong, &protocol.Pong{
+				ID:         hostname + "_" + conn.LocalAddr().String(),
+				ClientTime: time.Now().String(),
+			})
+			if err != nil {
+				logs.Error("encode node-pong error %v", err)
+				continue
 			}
 			// write
-			responseData := utils.StructSerialize(resultData)
-			conn.WriteMessage(msgType, []byte(responseData))
-			logs.Debug("output message, type(1=text, 2=binary): %+v, msg: %+v\n", msgType, responseData)
-		} else {
+			conn.WriteMessage(msgType, responseData)
+			logs.Debug("output message, type(1=text, 2=binary): %+v, msg: %+v\n", msgType, string(responseData))
+		} else if registry == nil {
 			// write
 			conn.WriteMessage(msgType, msgData)
 			logs.Debug("output message, type(1=text, 2=binary): %+v, msg: %+v\n", msgType, string(msgData))
 		}
 	}
 }
+
+// verifyHello checks the secret of the hello emit signed with the WsPass
+func verifyHello(wsPass string, msg *protocol.Message) bool {
+	var hello protocol.Hello
+	if err := msg.Decode(&hello); err != nil {
+		logs.Debug("decode hello error %v", err)
+		return false
+	}
+	if hello.Secret == "" {
+		return false
+	}
+	return utils.VerifySecret(wsPass, hello.ID, hello.Timestamp, hello.Secret, maxSecretSkew)
+}
+
+// closeWithCode sends the close frame with the code and reason to the agent
+func closeWithCode(conn *websocket.Conn, code int, reason string) {
+	deadline := time.Now().Add(time.Second)
+	if err := conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline); err != nil {
+		logs.Debug("write close message error %v", err)
+	}
+}
@@ -6,8 +6,11 @@
 package cmd
 
 import (
+	"context"
 	"log"
-	"sync"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/cobra"
 	"golang.org/x/sync/errgroup"
@@ -18,7 +21,6 @@ import (
 
 var (
 	configFile *string
-	g          errgroup.Group
 )
 
 // startCmd represents the start command
@@ -30,20 +32,30 @@ var startCmd = &cobra.Command{
 		start the monitor-api.`,
 
 	Run: func(cmd *cobra.Command, args []string) {
-		var wg sync.WaitGroup
-
 		// config init
 		config.Init(*configFile)
 
+		// SIGINT and SIGTERM cancel the root context, a second signal exits at once
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+		go func() {
+			sig := <-signals
+			log.Printf("receive signal %v, shutting down", sig)
+			signal.Stop(signals)
+			cancel()
+		}()
+
+		// a failing server or node service stops the others too
+		g, ctx := errgroup.WithContext(ctx)
+
 		// init server, if modify the config should write above this line
-		server.Start(&g)
+		server.Start(ctx, g)
 
 		if err := g.Wait(); err != nil {
 			log.Fatal(err)
 		}
-
-		wg.Add(1)
-		wg.Wait()
 	},
 }
 
@@ -7,15 +7,14 @@ package config
 
 import (
 	"crypto/tls"
-	"encoding/json"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -47,7 +46,8 @@ type ServerConfig struct {
 	MaxHeaderBytes    int
 	ReadTimeout       time.Duration
 	ReadHeaderTimeout time.Duration
-	LogLevel          logrus.Level // log level
+	LogLevel          logrus.Level  // log level
+	ReloadInterval    time.Duration // poll the config files for changes every interval, 0 means no reload
 	TLSConfig         *tls.Config
 	onShutdown        []func()
 	WriteTimeout      time.Duration
@@ -59,9 +59,19 @@ type ServerConfig struct {
 	EnableWebSocket bool
 	WebSocketConfig *WebSocketConfig
 
+	// Metrics config, serve /metrics in the Prometheus text format
+	EnableMetrics bool
+
+	// Aggregator config, keep the emits of the agents connected to /api
+	EnableAggregator bool
+	AggregatorConfig *AggregatorConfig
+
 	// RPC config
 	EnableRPC bool
 	RPCConfig *RPCConfig
+
+	// Nodes config, empty means only the node of RPCConfig
+	Nodes []*NodeConfig
 }
 
 // EngineConfig for the router config
@@ -73,24 +83,85 @@ type EngineConfig struct {
 	WriteLog            bool
 }
 
+// AggregatorConfig for the aggregator config
+type AggregatorConfig struct {
+	OfflineTimeout time.Duration // a node without emits for this time is offline
+	Retention      time.Duration // an offline node is dropped after this time without emits
+	MaxNodes       int           // max nodes kept, the offline node seen least recently is dropped first
+}
+
 // RPCConfig for the rpc config
 type RPCConfig struct {
-	Debug  bool
-	Scheme string
-	URL    string
+	Debug       bool
+	Scheme      string
+	URL         string
+	PoolSize    int           // max idle connections kept to the node
+	IdleTimeout time.Duration // idle connections older than this are redialed
+	Timeout     time.Duration // default timeout of every rpc call, 0 means no timeout
+
+	FailbackInterval time.Duration // interval to try the primary url again after a failover, 0 means never
+
+	// http and https transport config
+	Username string            // basic auth username
+	Password string            // basic auth password
+	Headers  map[string]string // extra headers of every request
+	CAFile   string            // CA certificate file to verify the https node
+}
+
+// NodeConfig is a go-seele node monitored by this process
+type NodeConfig struct {
+	Name         string // node name, used in the logs and the journal file
+	RPCURL       string // RPC server addrs for go-seele node separated by ';', the primary first
+	InstanceName string // name to display on the monitoring page, default is INSTANCE_NAME or hostname
+	Shard        int    // shard override to choose the monitor server, -1 means use the node shard
+}
+
+// GetNodeConfigs returns the monitored nodes, if no Nodes configured returns
+// the single node of RPCConfig
+func (c *ServerConfig) GetNodeConfigs() []*NodeConfig {
+	if len(c.Nodes) != 0 {
+		return c.Nodes
+	}
+	return []*NodeConfig{{
+		Name:   "default",
+		RPCURL: c.RPCConfig.URL,
+		Shard:  -1,
+	}}
+}
+
+// RPCURLs returns the urls of the node, the primary first then the standby urls
+func (n *NodeConfig) RPCURLs() []string {
+	var urls []string
+	for _, url := range strings.Split(n.RPCURL, ";") {
+		if url = strings.TrimSpace(url); url != "" {
+			urls = append(urls, url)
+		}
+	}
+	return urls
 }
 
 // WebSocketConfig is the base webSocket config
 type WebSocketConfig struct {
-	DelayReConnTime       time.Duration // delay recon time when web socket error occur
-	DelaySendTime         time.Duration // delay recon and resend msg to monitor when rpc server occur error
+	DelayReConnTime       time.Duration // initial delay to reconnect when web socket error occur
+	DelaySendTime         time.Duration // initial delay to recon and resend msg to monitor when rpc server occur error
+	BackoffMaxDelay       time.Duration // max delay of the reconnect and resend backoff
+	BackoffMultiplier     float64       // growth of the delay after each failed attempt
+	BackoffJitter         float64       // the delays are randomized within ±BackoffJitter of themselves, in [0, 1]
+	BackoffResetAfter     time.Duration // the delays start again from the initial delays after a connection up for this time
 	ReportErrorAfterTimes int           // report the error when error occur over the special times
+	ReadyReportPeriods    int           // not ready if no report within the periods of WsFullEventTickerTime
 
 	WsFullEventTickerTime        time.Duration // send msg with ticker
 	WsLatestBlockEventTickerTime time.Duration // send msg with ticker
 	WsPass                       string        // Password to authorize access to the monitoring page
 	WsRouter                     string        // full path host:port/ws and the WsRouter is /ws
 	WsURL                        string        // host:port
+
+	EnableJournal     bool          // journal the emits to TempFolder when the monitor server is unreachable
+	JournalMaxEntries int           // max emits kept in the journal
+	JournalMaxBytes   int64         // max size of the journal file
+	JournalMaxAge     time.Duration // emits older than this are dropped instead of replayed
+	JournalDropPolicy string        // which emits are dropped when the journal is full: oldest | newest
 }
 
 var (
@@ -101,7 +172,7 @@ var (
 
 	// appConfigPath is the path to the config files
 	appConfigPath string
-	// appConfigProvider is the provider for the config, default is ini
+	// appConfigProvider is the provider for the config, chosen by the extension of the config file, default is ini
 	appConfigProvider = "ini"
 
 	// SeeleConfig is the default config for Application
@@ -111,36 +182,57 @@ var (
 // Init init the config
 func Init(configFile string) {
 	SeeleConfig = newSeeleConfig()
+	appConfigProvider = configProvider(configFile)
 	var err error
 	if err = parseConfig(configFile); err != nil {
 		panic(err)
 	}
-	var monitorConfigFile string
-	if monitorConfigFile = os.Getenv("MONITOR_CONFIG_FILE"); monitorConfigFile == "" {
-		monitorConfigFile = SeeleConfig.MonitorConfigFile
-	}
-
-	shardConfig, err := GetConfigFromFile(monitorConfigFile)
+	shardServers, err := GetMonitorServersFromFile(SeeleConfig.monitorConfigPath())
 	if err != nil {
 		fmt.Println(err.Error())
 		return
 	}
-	ShardMap = shardConfig
+	SetMonitorServers(shardServers)
 
 }
 
+// monitorConfigPath returns the monitor config file, env MONITOR_CONFIG_FILE
+// overrides MonitorConfigFile
+func (c *Config) monitorConfigPath() string {
+	if monitorConfigFile := os.Getenv("MONITOR_CONFIG_FILE"); monitorConfigFile != "" {
+		return monitorConfigFile
+	}
+	return c.MonitorConfigFile
+}
+
 func newSeeleConfig() *Config {
 	defaultAddr := ":9999"
 
 	defaultDelayReConnTime := 5 * time.Second
 	defaultDelaySendTime := 5 * time.Second
+	defaultBackoffMaxDelay := 5 * time.Minute
+	defaultBackoffResetAfter := 60 * time.Second
 
 	defaultLogLevel := InfoLevel
+	defaultReloadInterval := 5 * time.Second
 
 	defaultWsFullEventTickerTime := 10 * time.Second
 	defaultWsLatestEventTickerTime := 5 * time.Second
 	defaultWsRouter := "/api"
 
+	defaultRPCPoolSize := 2
+	defaultRPCIdleTimeout := 60 * time.Second
+	defaultRPCTimeout := 10 * time.Second
+	defaultRPCFailbackInterval := 30 * time.Second
+
+	defaultJournalMaxEntries := 10000
+	defaultJournalMaxBytes := int64(16 << 20) // 16MB
+	defaultJournalMaxAge := 24 * time.Hour
+
+	defaultAggregatorOfflineTimeout := 60 * time.Second
+	defaultAggregatorRetention := 24 * time.Hour
+	defaultAggregatorMaxNodes := 10000
+
 	return &Config{
 		AppName:      APPName,
 		RecoverPanic: true,
@@ -150,6 +242,7 @@ func newSeeleConfig() *Config {
 			Addr:              defaultAddr,
 			IdleTimeout:       0,
 			LogLevel:          defaultLogLevel,
+			ReloadInterval:    defaultReloadInterval,
 			MaxHeaderBytes:    1 << 20, //1MB
 			ReadTimeout:       300 * time.Second,
 			ReadHeaderTimeout: 60 * time.Second,
@@ -169,224 +262,160 @@ func newSeeleConfig() *Config {
 				WsLatestBlockEventTickerTime: defaultWsLatestEventTickerTime,
 				DelayReConnTime:              defaultDelayReConnTime,
 				DelaySendTime:                defaultDelaySendTime,
+				BackoffMaxDelay:              defaultBackoffMaxDelay,
+				BackoffMultiplier:            2,
+				BackoffJitter:                0.2,
+				BackoffResetAfter:            defaultBackoffResetAfter,
 				ReportErrorAfterTimes:        10,
+				ReadyReportPeriods:           3,
 				WsPass:                       "",
+				EnableJournal:                false,
+				JournalMaxEntries:            defaultJournalMaxEntries,
+				JournalMaxBytes:              defaultJournalMaxBytes,
+				JournalMaxAge:                defaultJournalMaxAge,
+				JournalDropPolicy:            JournalDropOldest,
+			},
+			EnableMetrics:    false,
+			EnableAggregator: false,
+			AggregatorConfig: &AggregatorConfig{
+				OfflineTimeout: defaultAggregatorOfflineTimeout,
+				Retention:      defaultAggregatorRetention,
+				MaxNodes:       defaultAggregatorMaxNodes,
 			},
 			EnableRPC: false,
 			RPCConfig: &RPCConfig{
-				URL:    "", // rpc url
-				Scheme: "tcp",
-				Debug:  false,
+				URL:         "", // rpc url
+				Scheme:      "tcp",
+				Debug:       false,
+				PoolSize:    defaultRPCPoolSize,
+				IdleTimeout: defaultRPCIdleTimeout,
+				Timeout:     defaultRPCTimeout,
+
+				FailbackInterval: defaultRPCFailbackInterval,
 			},
 		},
 	}
 }
 
-// parseConfig only support ini
-func parseConfig(appConfigPath string) (err error) {
-	AppConfig, err = newAppConfig(appConfigProvider, appConfigPath)
+// configProvider returns the provider of the config file by its extension,
+// .json, .yaml or .yml, ini otherwise
+func configProvider(configFile string) string {
+	switch strings.ToLower(filepath.Ext(configFile)) {
+	case ".json":
+		return "json"
+	case ".yaml", ".yml":
+		return "yaml"
+	}
+	return "ini"
+}
+
+// parseConfig parse the config file with appConfigProvider
+func parseConfig(configPath string) (err error) {
+	AppConfig, err = newAppConfig(appConfigProvider, configPath)
 	if err != nil {
 		return err
 	}
-	return assignConfig(AppConfig)
+	if err = assignConfig(SeeleConfig, AppConfig); err != nil {
+		return err
+	}
+	appConfigPath = configPath
+	APPName = SeeleConfig.AppName
+	return nil
 }
 
-// assignConfig assign the config
-func assignConfig(ac config.Configure) error {
-	for _, i := range []interface{}{SeeleConfig, &SeeleConfig.ServerConfig} {
+// assignConfig assign the config of ac into target
+func assignConfig(target *Config, ac config.Configure) error {
+	for _, i := range []interface{}{target, &target.ServerConfig} {
 		assignSingleConfig(i, ac)
 	}
 	// set the run mode first, env set is the highest priority
-	if envRunMode := os.Getenv("MONITOR_API_RUNMODE"); envRunMode != "" {
-		SeeleConfig.RunMode = envRunMode
+	if envRunMode := os.Getenv(envKey("RunMode")); envRunMode != "" {
+		target.RunMode = envRunMode
 	} else if runMode := ac.String("RunMode"); runMode != "" {
-		SeeleConfig.RunMode = runMode
+		target.RunMode = runMode
 	} else {
 		defaultEnv, err := ac.GetSection("default")
 		if err != nil {
-			SeeleConfig.RunMode = DEV
+			target.RunMode = DEV
 		} else {
-			SeeleConfig.RunMode = defaultEnv["run_mode"]
+			target.RunMode = defaultEnv["run_mode"]
 		}
 	}
 
-	// APPName load from default, default section, real section
-	currentAppName := APPName
-	monitorConfigFile := "monitor.json"
-	tempFolder := os.TempDir()
-
-	if defaultSection, err := ac.GetSection("default"); err == nil {
-		if len(defaultSection["app_name"]) != 0 {
-			currentAppName = defaultSection["app_name"]
-		}
-		if len(defaultSection["monitorconfigfile"]) != 0 {
-			monitorConfigFile = defaultSection["monitorconfigfile"]
-		}
-		if len(defaultSection["tempfolder"]) != 0 {
-			tempFolder = defaultSection["tempfolder"]
-		}
-	}
-	SeeleConfig.MonitorConfigFile = monitorConfigFile
-
-	// first use default section, and use real mode to override
-	currentSection, err := ac.GetSection(SeeleConfig.RunMode)
+	// first use default section, and use real mode and env to override
+	defaultSection, _ := ac.GetSection("default")
+	runModeSection, err := ac.GetSection(target.RunMode)
 	if err != nil {
-		SeeleConfig.RunMode = DEV
-		currentSection, _ = ac.GetSection(SeeleConfig.RunMode)
+		target.RunMode = DEV
+		runModeSection, _ = ac.GetSection(target.RunMode)
 	}
-	// real APPName
-	if currentSection["app_name"] != "" {
-		currentAppName = currentSection["app_name"]
-	}
-	SeeleConfig.AppName = currentAppName
-	APPName = currentAppName
+	currentSection := runModeSettings(defaultSection, runModeSection)
 
-	currentServerConfig := SeeleConfig.ServerConfig
-	if currentSection["addr"] != "" {
-		currentServerConfig.Addr = currentSection["addr"]
-	}
-	if currentSection["readtimeout"] != "" {
-		currentReadTimeout, err := time.ParseDuration(currentSection["readtimeout"] + DefaultTimeUnit)
-		if err == nil {
-			currentServerConfig.ReadTimeout = currentReadTimeout
-		}
-	}
-	if currentSection["readheadertimeout"] != "" {
-		currentReadHeaderTimeout, err := time.ParseDuration(currentSection["readheadertimeout"] + DefaultTimeUnit)
-		if err == nil {
-			currentServerConfig.ReadHeaderTimeout = currentReadHeaderTimeout
-		}
-	}
-	if currentSection["writetimeout"] != "" {
-		currentWriteTimeout, err := time.ParseDuration(currentSection["writetimeout"] + DefaultTimeUnit)
-		if err == nil {
-			currentServerConfig.WriteTimeout = currentWriteTimeout
-		}
-	}
-	if currentSection["idletimeout"] != "" {
-		currentIdleTimeout, err := time.ParseDuration(currentSection["idletimeout"] + DefaultTimeUnit)
-		if err == nil {
-			currentServerConfig.IdleTimeout = currentIdleTimeout
+	target.AppName = APPName
+	target.MonitorConfigFile = "monitor.json"
+	target.ServerConfig.EngineConfig.TempFolder = os.TempDir()
+	assignSettings(target, currentSection)
+
+	if currentSection[nodesKey] != "" {
+		nodes, err := parseNodeConfigs(ac, strings.Split(currentSection[nodesKey], ";"))
+		if err != nil {
+			return err
 		}
+		target.ServerConfig.Nodes = nodes
 	}
-	if currentSection["maxheaderbytes"] != "" {
-		currentMaxHeaderBytes, err := strconv.Atoi(currentSection["maxheaderbytes"])
-		if err == nil {
-			currentServerConfig.MaxHeaderBytes = currentMaxHeaderBytes
+	return nil
+}
+
+// parseHeaders parse the headers in format "key: value;key2: value2"
+func parseHeaders(value string) map[string]string {
+	headers := make(map[string]string)
+	for _, header := range strings.Split(value, ";") {
+		kv := strings.SplitN(header, ":", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			continue
 		}
+		headers[strings.TrimSpace(kv[0])] = strings.TrimSpace(kv[1])
 	}
+	return headers
+}
 
-	if currentSection["loglevel"] != "" {
-		currentLogLevel, ok := LogLevelMap[currentSection["loglevel"]]
-		if !ok {
-			currentLogLevel = InfoLevel
-		}
-		currentServerConfig.LogLevel = currentLogLevel
-	}
-
-	if currentSection["enablewebsocket"] != "" {
-		currentEnableWebSocket, err := strconv.ParseBool(currentSection["enablewebsocket"])
-		if err == nil {
-			currentServerConfig.EnableWebSocket = currentEnableWebSocket
-
-			// web socket config deal
-			currentWebSocketConfig := currentServerConfig.WebSocketConfig
-			if currentWebSocketConfig != nil {
-				if currentSection["wsurl"] != "" {
-					currentWebSocketURL := currentSection["wsurl"]
-					if err == nil {
-						currentWebSocketConfig.WsURL = currentWebSocketURL
-					}
-				}
-				if currentSection["wsrouter"] != "" {
-					currentWsRouter := currentSection["wsrouter"]
-					if err == nil {
-						currentWebSocketConfig.WsRouter = currentWsRouter
-					}
-				}
-				if currentSection["wsfulleventtickertime"] != "" {
-					currentWsFullEventTickerTime, err := time.ParseDuration(currentSection["wsfulleventtickertime"] + DefaultTimeUnit)
-					if err == nil {
-						currentWebSocketConfig.WsFullEventTickerTime = currentWsFullEventTickerTime
-					}
-				}
-				if currentSection["wslatestblockeventtickertime"] != "" {
-					currentWsLatestBlockEventTickerTime, err := time.ParseDuration(currentSection["wslatestblockeventtickertime"] + DefaultTimeUnit)
-					if err == nil {
-						currentWebSocketConfig.WsLatestBlockEventTickerTime = currentWsLatestBlockEventTickerTime
-					}
-				}
-				if currentSection["delayreconntime"] != "" {
-					currentDelayReConnTime, err := time.ParseDuration(currentSection["delayreconntime"] + DefaultTimeUnit)
-					if err == nil {
-						currentWebSocketConfig.DelayReConnTime = currentDelayReConnTime
-					}
-				}
-				if currentSection["delaysendtime"] != "" {
-					currentDelaySendTime, err := time.ParseDuration(currentSection["delaysendtime"] + DefaultTimeUnit)
-					if err == nil {
-						currentWebSocketConfig.DelaySendTime = currentDelaySendTime
-					}
-				}
-				if currentSection["reporterroraftertimes"] != "" {
-					currentReportErrorAfterTimes, err := strconv.Atoi(currentSection["reporterroraftertimes"])
-					if err == nil {
-						currentWebSocketConfig.ReportErrorAfterTimes = currentReportErrorAfterTimes
-					}
-				}
-
-				currentServerConfig.WebSocketConfig = currentWebSocketConfig
-			}
+// parseNodeConfigs parse the node sections [node.<name>] of the names
+func parseNodeConfigs(ac config.Configure, names []string) ([]*NodeConfig, error) {
+	var nodes []*NodeConfig
+	seen := make(map[string]bool)
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
 		}
-	}
-	if currentSection["enablerpc"] != "" {
-		currentEnableRPC, err := strconv.ParseBool(currentSection["enablerpc"])
-		if err == nil {
-			currentServerConfig.EnableRPC = currentEnableRPC
-
-			// rpc config deal
-			currentRPCConfig := currentServerConfig.RPCConfig
-			if currentRPCConfig != nil {
-				if currentSection["rpcurl"] != "" {
-					currentRPCConfig.URL = currentSection["rpcurl"]
-				}
-				currentServerConfig.RPCConfig = currentRPCConfig
-			}
+		if seen[name] {
+			return nil, fmt.Errorf("node %v configured twice", name)
 		}
-	}
+		seen[name] = true
 
-	// engine config deal
-	currentEngineConfig := currentServerConfig.EngineConfig
-	if currentEngineConfig != nil {
-		if currentSection["limitconnection"] != "" {
-			currentLimitConnection, err := strconv.Atoi(currentSection["limitconnection"])
-			if err == nil {
-				currentEngineConfig.LimitConnection = currentLimitConnection
-			}
+		section, err := ac.GetSection(NodeSectionPrefix + strings.ToLower(name))
+		if err != nil {
+			return nil, fmt.Errorf("node %v section [%v%v] error: %v", name, NodeSectionPrefix, name, err)
 		}
-		if currentSection["disableconsolecolor"] != "" {
-			currentDisableConsoleColor, err := strconv.ParseBool(currentSection["disableconsolecolor"])
-			if err == nil {
-				currentEngineConfig.DisableConsoleColor = currentDisableConsoleColor
-			}
+		node := &NodeConfig{
+			Name:         name,
+			RPCURL:       section["rpcurl"],
+			InstanceName: section["instancename"],
+			Shard:        -1,
 		}
-		if currentSection["writelog"] != "" {
-			currentWriteLog, err := strconv.ParseBool(currentSection["writelog"])
-			if err == nil {
-				currentEngineConfig.WriteLog = currentWriteLog
-				if currentSection["logfile"] != "" {
-					currentEngineConfig.LogFile = currentSection["logfile"]
-				}
-			}
+		if node.RPCURL == "" {
+			return nil, fmt.Errorf("node %v RPCURL should not be empty", name)
 		}
-		if currentSection["tempfolder"] != "" {
-			tempFolder = currentSection["tempfolder"]
+		if section["shard"] != "" {
+			shard, err := strconv.Atoi(section["shard"])
+			if err != nil || shard < 0 {
+				return nil, fmt.Errorf("node %v invalid shard %v", name, section["shard"])
+			}
+			node.Shard = shard
 		}
-		currentEngineConfig.TempFolder = tempFolder
-		currentServerConfig.EngineConfig = currentEngineConfig
+		nodes = append(nodes, node)
 	}
-
-	return nil
+	return nodes, nil
 }
 
 func assignSingleConfig(p interface{}, ac config.Configure) {
@@ -570,14 +599,12 @@ func (b *MonitorAppConfig) SaveConfigFile(filename string) error {
 	return b.innerConfig.SaveConfigFile(filename)
 }
 
-// GetConfigFromFile unmarshals the config from the given file
+// GetConfigFromFile returns the url of the first monitor server of every
+// shard in the given file
 func GetConfigFromFile(filepath string) (map[string]string, error) {
-	var config map[string]string
-	buff, err := ioutil.ReadFile(filepath)
+	servers, err := GetMonitorServersFromFile(filepath)
 	if err != nil {
-		return config, err
+		return nil, err
 	}
-
-	err = json.Unmarshal(buff, &config)
-	return config, err
+	return firstURLs(servers), nil
 }
@@ -7,9 +7,17 @@ package config
 
 import (
 	"fmt"
+	"io/ioutil"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/seeleteam/monitor-api/core/config"
 )
 
 func TestConfigPath(t *testing.T) {
@@ -20,3 +28,161 @@ func TestConfigPath(t *testing.T) {
 	appConfigPath := filepath.Join(workPath, "conf", "app.conf")
 	fmt.Printf("appConfigPath is %s\n", appConfigPath)
 }
+
+func TestParseNodeConfigs(t *testing.T) {
+	ac, err := config.NewConfigData("ini", []byte(`
+[node.alpha]
+RPCURL = 127.0.0.1:55027
+InstanceName = alpha-1
+
+[node.beta]
+RPCURL = 127.0.0.1:55028; http://127.0.0.1:8037
+Shard = 2
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	nodes, err := parseNodeConfigs(ac, []string{"alpha", " beta", ""})
+	assert.NoError(t, err)
+	assert.Equal(t, []*NodeConfig{
+		{Name: "alpha", RPCURL: "127.0.0.1:55027", InstanceName: "alpha-1", Shard: -1},
+		{Name: "beta", RPCURL: "127.0.0.1:55028; http://127.0.0.1:8037", Shard: 2},
+	}, nodes)
+	assert.Equal(t, []string{"127.0.0.1:55027"}, nodes[0].RPCURLs())
+	assert.Equal(t, []string{"127.0.0.1:55028", "http://127.0.0.1:8037"}, nodes[1].RPCURLs())
+
+	_, err = parseNodeConfigs(ac, []string{"alpha", "alpha"})
+	assert.Error(t, err)
+	_, err = parseNodeConfigs(ac, []string{"gamma"})
+	assert.Error(t, err)
+}
+
+func TestInitYAML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monitor-api-yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Unsetenv("MONITOR_API_RUNMODE")
+	os.Unsetenv("MONITOR_CONFIG_FILE")
+
+	monitorFile := filepath.Join(dir, "monitor.json")
+	if err = ioutil.WriteFile(monitorFile, []byte(`{"1": "127.0.0.1:3000"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	appConf := `run_mode: dev
+MonitorConfigFile: ` + monitorFile + `
+dev:
+  addr: ":9997"
+  LogLevel: warn
+  EnableWebSocket: true
+  WsFullEventTickerTime: 20
+  EnableRPC: true
+  Nodes: [alpha]
+node.alpha:
+  RPCURL: 127.0.0.1:55027
+`
+	configFile := filepath.Join(dir, "app.yml")
+	if err = ioutil.WriteFile(configFile, []byte(appConf), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, "yaml", configProvider(configFile))
+	assert.Equal(t, "json", configProvider("app.JSON"))
+	assert.Equal(t, "ini", configProvider("app.conf"))
+
+	Init(configFile)
+	defer func() { appConfigProvider = "ini" }()
+	assert.Equal(t, ":9997", SeeleConfig.ServerConfig.Addr)
+	assert.Equal(t, logrus.WarnLevel, SeeleConfig.ServerConfig.LogLevel)
+	assert.Equal(t, 20*time.Second, SeeleConfig.ServerConfig.WebSocketConfig.WsFullEventTickerTime)
+	assert.Equal(t, []*NodeConfig{{Name: "alpha", RPCURL: "127.0.0.1:55027", Shard: -1}}, SeeleConfig.ServerConfig.Nodes)
+	assert.Equal(t, "127.0.0.1:3000", ShardMap["1"])
+}
+
+func TestAssignConfigEnv(t *testing.T) {
+	ac, err := config.NewConfigData("ini", []byte(`
+run_mode = dev
+addr = :9990
+LogLevel = error
+WsPass = ${MONITOR_API_TEST_PASS||fallback}
+
+[dev]
+addr = :9997
+LogLevel = warn
+EnableWebSocket = true
+WsFullEventTickerTime = 20
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Unsetenv("MONITOR_API_RUNMODE")
+	os.Setenv("MONITOR_API_ADDR", ":9998")
+	os.Setenv("MONITOR_API_RPCURL", "${MONITOR_API_TEST_RPC}")
+	os.Setenv("MONITOR_API_TEST_RPC", "127.0.0.1:55029")
+	os.Setenv("MONITOR_API_ENABLERPC", "true")
+	defer func() {
+		for _, key := range []string{"MONITOR_API_ADDR", "MONITOR_API_RPCURL", "MONITOR_API_TEST_RPC", "MONITOR_API_ENABLERPC"} {
+			os.Unsetenv(key)
+		}
+	}()
+
+	c := newSeeleConfig()
+	assert.NoError(t, assignConfig(c, ac))
+	// env > run mode section > default section > built-in default
+	assert.Equal(t, ":9998", c.ServerConfig.Addr)
+	assert.Equal(t, logrus.WarnLevel, c.ServerConfig.LogLevel)
+	assert.Equal(t, "fallback", c.ServerConfig.WebSocketConfig.WsPass)
+	assert.Equal(t, 20*time.Second, c.ServerConfig.WebSocketConfig.WsFullEventTickerTime)
+	assert.Equal(t, 5*time.Second, c.ServerConfig.WebSocketConfig.WsLatestBlockEventTickerTime)
+	assert.Equal(t, "127.0.0.1:55029", c.ServerConfig.RPCConfig.URL)
+	assert.True(t, c.ServerConfig.EnableRPC)
+	assert.Equal(t, "MONITOR_API_WSPASS", envKey("wspass"))
+}
+
+func TestConfigSettings(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, key := range configKeys() {
+		assert.Equal(t, strings.ToLower(key), key)
+		assert.False(t, seen[key], key)
+		seen[key] = true
+	}
+
+	ac, err := config.NewConfigData("ini", []byte(`
+run_mode = dev
+
+[dev]
+addr = :9997
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	os.Unsetenv("MONITOR_API_RUNMODE")
+	env := map[string]string{
+		"MONITOR_API_SERVERNAME":   "monitor-api:test",
+		"MONITOR_API_RECOVERPANIC": "false",
+		"MONITOR_API_RPCURL":       "127.0.0.1:55030",
+		"MONITOR_API_WSPASS":       "pass",
+		"MONITOR_API_RPCDEBUG":     "true",
+	}
+	for key, value := range env {
+		os.Setenv(key, value)
+	}
+	defer func() {
+		for key := range env {
+			os.Unsetenv(key)
+		}
+	}()
+
+	c := newSeeleConfig()
+	assert.NoError(t, assignConfig(c, ac))
+	assert.Equal(t, "monitor-api:test", c.ServerName)
+	assert.False(t, c.RecoverPanic)
+	// the settings of a feature apply without its enable key
+	assert.False(t, c.ServerConfig.EnableRPC)
+	assert.Equal(t, "127.0.0.1:55030", c.ServerConfig.RPCConfig.URL)
+	assert.True(t, c.ServerConfig.RPCConfig.Debug)
+	assert.False(t, c.ServerConfig.EnableWebSocket)
+	assert.Equal(t, "pass", c.ServerConfig.WebSocketConfig.WsPass)
+}
@@ -0,0 +1,46 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package config
+
+import (
+	"os"
+	"strings"
+
+	"github.com/seeleteam/monitor-api/core/config"
+)
+
+// EnvPrefix prefixes the env overriding a config key, MONITOR_API_<KEY> with
+// the key in upper case, like MONITOR_API_WSPASS for WsPass
+const EnvPrefix = "MONITOR_API_"
+
+// envKey returns the env overriding the config key
+func envKey(key string) string {
+	return EnvPrefix + strings.ToUpper(key)
+}
+
+// runModeSettings returns the settings of the run mode, the env overrides
+// the run mode section which overrides the default section. The values are
+// expanded by config.ExpandValueEnv.
+func runModeSettings(defaultSection, runModeSection map[string]string) map[string]string {
+	keys := configKeys()
+	settings := make(map[string]string, len(keys))
+	for _, section := range []map[string]string{defaultSection, runModeSection} {
+		for key, value := range section {
+			if value != "" {
+				settings[key] = value
+			}
+		}
+	}
+	for _, key := range keys {
+		if value := os.Getenv(envKey(key)); value != "" {
+			settings[key] = value
+		}
+	}
+	for key, value := range settings {
+		settings[key] = config.ExpandValueEnv(value)
+	}
+	return settings
+}
@@ -16,7 +16,7 @@ var (
 	// VERSION represent seele monitor api version.
 	VERSION = "0.1.0"
 
-	// ShardMap shard:<websocket url>
+	// ShardMap shard:<websocket url of the first monitor server>, see MonitorServers
 	ShardMap map[string]string
 )
 
@@ -36,6 +36,15 @@ const (
 
 	// DefaultTimeUnit default time unit for config
 	DefaultTimeUnit = DefaultTimeUnitSecond
+
+	// NodeSectionPrefix prefix of the node sections, [node.<name>]
+	NodeSectionPrefix = "node."
+
+	// JournalDropOldest drop the oldest emits when the journal is full
+	JournalDropOldest = "oldest"
+
+	// JournalDropNewest drop the new emits when the journal is full
+	JournalDropNewest = "newest"
 )
 
 const (
@@ -0,0 +1,224 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+// liveSettings are the settings applied by Apply without a restart
+var liveSettings = map[string]bool{
+	"ServerConfig.LogLevel":                                     true,
+	"ServerConfig.WebSocketConfig.WsFullEventTickerTime":        true,
+	"ServerConfig.WebSocketConfig.WsLatestBlockEventTickerTime": true,
+	"ServerConfig.WebSocketConfig.ReportErrorAfterTimes":        true,
+}
+
+// settingsMu guards the live settings of SeeleConfig, Apply changes them
+// while the services run
+var settingsMu sync.RWMutex
+
+// WebSocketSettings returns a copy of the web socket config of SeeleConfig,
+// nil if not set. It is safe while a reload is applied, unlike reading
+// SeeleConfig.ServerConfig.WebSocketConfig.
+func WebSocketSettings() *WebSocketConfig {
+	settingsMu.RLock()
+	defer settingsMu.RUnlock()
+	if SeeleConfig.ServerConfig.WebSocketConfig == nil {
+		return nil
+	}
+	wsConfig := *SeeleConfig.ServerConfig.WebSocketConfig
+	return &wsConfig
+}
+
+// Reload is the change of the config files found by a Watcher
+type Reload struct {
+	Config         *Config                    // the new config, SeeleConfig is unchanged until Apply
+	Servers        map[string][]MonitorServer // the new monitor servers
+	Live           []string                   // the changed settings Apply applies
+	Restart        []string                   // the changed settings that require a restart
+	ServersChanged bool                       // the monitor servers changed, Apply applies them
+}
+
+// Changed reports whether Apply has something to apply
+func (r *Reload) Changed() bool {
+	return len(r.Live) > 0 || r.ServersChanged
+}
+
+// Apply copies the live settings of the reload into SeeleConfig under
+// settingsMu and replaces the monitor servers, the settings that require a
+// restart are kept
+func (r *Reload) Apply() {
+	settingsMu.Lock()
+	defer settingsMu.Unlock()
+	serverConfig := SeeleConfig.ServerConfig
+	serverConfig.LogLevel = r.Config.ServerConfig.LogLevel
+	wsConfig, newWsConfig := serverConfig.WebSocketConfig, r.Config.ServerConfig.WebSocketConfig
+	wsConfig.WsFullEventTickerTime = newWsConfig.WsFullEventTickerTime
+	wsConfig.WsLatestBlockEventTickerTime = newWsConfig.WsLatestBlockEventTickerTime
+	wsConfig.ReportErrorAfterTimes = newWsConfig.ReportErrorAfterTimes
+	if r.ServersChanged {
+		SetMonitorServers(r.Servers)
+	}
+}
+
+// Validate checks the settings a reload may apply live
+func (c *Config) Validate() error {
+	wsConfig := c.ServerConfig.WebSocketConfig
+	if wsConfig == nil {
+		return fmt.Errorf("WebSocketConfig is nil")
+	}
+	if wsConfig.WsFullEventTickerTime <= 0 || wsConfig.WsLatestBlockEventTickerTime <= 0 {
+		return fmt.Errorf("WsFullEventTickerTime and WsLatestBlockEventTickerTime should be positive")
+	}
+	if wsConfig.ReportErrorAfterTimes < 0 {
+		return fmt.Errorf("ReportErrorAfterTimes should not be negative")
+	}
+	return nil
+}
+
+// load reads the config file and the monitor config file into a new config,
+// the monitor config file is the one of the startup as MonitorConfigFile
+// requires a restart
+func load(configPath, monitorConfigPath string) (*Config, map[string][]MonitorServer, error) {
+	ac, err := newAppConfig(appConfigProvider, configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := newSeeleConfig()
+	if err = assignConfig(c, ac); err != nil {
+		return nil, nil, err
+	}
+	if err = c.Validate(); err != nil {
+		return nil, nil, err
+	}
+	servers, err := GetMonitorServersFromFile(monitorConfigPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c, servers, nil
+}
+
+// changes returns the names of the settings that differ between the configs
+func changes(prefix string, old, updated reflect.Value, names []string) []string {
+	switch old.Kind() {
+	case reflect.Ptr:
+		if old.IsNil() || updated.IsNil() {
+			if old.IsNil() != updated.IsNil() {
+				names = append(names, prefix)
+			}
+			return names
+		}
+		return changes(prefix, old.Elem(), updated.Elem(), names)
+	case reflect.Struct:
+		if prefix != "" {
+			prefix += "."
+		}
+		for i := 0; i < old.NumField(); i++ {
+			field := old.Type().Field(i)
+			// the unexported fields, the handlers and the loggers are not settings
+			if field.PkgPath != "" || field.Type.Kind() == reflect.Func || field.Type.Kind() == reflect.Interface {
+				continue
+			}
+			names = changes(prefix+field.Name, old.Field(i), updated.Field(i), names)
+		}
+		return names
+	}
+	if !reflect.DeepEqual(old.Interface(), updated.Interface()) {
+		names = append(names, prefix)
+	}
+	return names
+}
+
+// newReload compares the loaded config with SeeleConfig, the settings that
+// require a restart are reported only if they changed since the last loaded
+// config, nil means none, so a pending restart is reported once per value
+func newReload(c *Config, servers map[string][]MonitorServer, last *Config) *Reload {
+	r := &Reload{Config: c, Servers: servers}
+	var changedSinceLast map[string]bool
+	if last != nil {
+		changedSinceLast = make(map[string]bool)
+		for _, name := range changes("", reflect.ValueOf(last), reflect.ValueOf(c), nil) {
+			changedSinceLast[name] = true
+		}
+	}
+	settingsMu.RLock()
+	names := changes("", reflect.ValueOf(SeeleConfig), reflect.ValueOf(c), nil)
+	settingsMu.RUnlock()
+	for _, name := range names {
+		if liveSettings[name] {
+			r.Live = append(r.Live, name)
+		} else if last == nil || changedSinceLast[name] {
+			r.Restart = append(r.Restart, name)
+		}
+	}
+	shardServers.RLock()
+	r.ServersChanged = !reflect.DeepEqual(shardServers.byShard, servers)
+	shardServers.RUnlock()
+	return r
+}
+
+// Watcher polls the mtime of the config file and the monitor config file
+// and loads them again when one changed
+type Watcher struct {
+	interval time.Duration
+	modTimes map[string]time.Time
+	last     *Config // the last valid config loaded, nil before the first
+
+	monitorConfigPath string // the monitor config file of the startup
+}
+
+// NewWatcher create a watcher of the config files loaded by Init
+func NewWatcher(interval time.Duration) *Watcher {
+	w := &Watcher{interval: interval, monitorConfigPath: SeeleConfig.monitorConfigPath()}
+	w.modTimes = w.stat()
+	return w
+}
+
+// stat returns the mtime of the config files, zero if missing
+func (w *Watcher) stat() map[string]time.Time {
+	modTimes := make(map[string]time.Time, 2)
+	for _, path := range []string{appConfigPath, w.monitorConfigPath} {
+		var modTime time.Time
+		if info, err := os.Stat(path); err == nil {
+			modTime = info.ModTime()
+		}
+		modTimes[path] = modTime
+	}
+	return modTimes
+}
+
+// Run polls the config files every interval until the ctx is done, the
+// changed and valid config is passed to reload, otherwise the error. A file
+// which failed is not loaded again until it changes.
+func (w *Watcher) Run(ctx context.Context, reload func(*Reload, error)) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		modTimes := w.stat()
+		if reflect.DeepEqual(modTimes, w.modTimes) {
+			continue
+		}
+		w.modTimes = modTimes
+		c, servers, err := load(appConfigPath, w.monitorConfigPath)
+		if err != nil {
+			reload(nil, err)
+			continue
+		}
+		reload(newReload(c, servers, w.last), nil)
+		w.last = c
+	}
+}
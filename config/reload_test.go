@@ -0,0 +1,127 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package config
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTestConfig(t *testing.T, dir string, logLevel, addr string, fullTicker int, monitorURL string) {
+	monitorFile := filepath.Join(dir, "monitor.json")
+	appConf := fmt.Sprintf(`MonitorConfigFile = %v
+run_mode = dev
+
+[dev]
+addr = %v
+LogLevel = %v
+EnableWebSocket = true
+WsFullEventTickerTime = %v
+`, monitorFile, addr, logLevel, fullTicker)
+	if err := ioutil.WriteFile(filepath.Join(dir, "app.conf"), []byte(appConf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(monitorFile, []byte(`{"1": "`+monitorURL+`"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	// the mtime changes even if the files are written within the fs time resolution
+	modTime := time.Now().Add(time.Duration(fullTicker) * time.Second)
+	os.Chtimes(filepath.Join(dir, "app.conf"), modTime, modTime)
+	os.Chtimes(monitorFile, modTime, modTime)
+}
+
+func nextReload(t *testing.T, w *Watcher) (*Reload, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	var (
+		reload *Reload
+		err    error
+	)
+	w.Run(ctx, func(r *Reload, e error) {
+		reload, err = r, e
+		cancel()
+	})
+	if reload == nil && err == nil {
+		t.Fatal("no reload")
+	}
+	return reload, err
+}
+
+func TestWatcher(t *testing.T) {
+	dir, err := ioutil.TempDir("", "monitor-api-reload")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	os.Unsetenv("MONITOR_API_RUNMODE")
+	os.Unsetenv("MONITOR_CONFIG_FILE")
+
+	writeTestConfig(t, dir, "info", ":9999", 10, "127.0.0.1:3000")
+	Init(filepath.Join(dir, "app.conf"))
+	assert.Equal(t, []MonitorServer{{URL: "127.0.0.1:3000"}}, MonitorServers("1"))
+	w := NewWatcher(10 * time.Millisecond)
+
+	writeTestConfig(t, dir, "debug", ":8888", 20, "127.0.0.1:3001")
+	reload, err := nextReload(t, w)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ServerConfig.LogLevel", "ServerConfig.WebSocketConfig.WsFullEventTickerTime"}, reload.Live)
+	assert.Equal(t, []string{"ServerConfig.Addr"}, reload.Restart)
+	assert.True(t, reload.ServersChanged)
+
+	reload.Apply()
+	assert.Equal(t, logrus.DebugLevel, SeeleConfig.ServerConfig.LogLevel)
+	assert.Equal(t, 20*time.Second, SeeleConfig.ServerConfig.WebSocketConfig.WsFullEventTickerTime)
+	assert.Equal(t, ":9999", SeeleConfig.ServerConfig.Addr)
+	assert.Equal(t, []MonitorServer{{URL: "127.0.0.1:3001"}}, MonitorServers("1"))
+	assert.Equal(t, "127.0.0.1:3001", ShardMap["1"])
+
+	// an invalid config is not applied
+	writeTestConfig(t, dir, "debug", ":9999", 0, "127.0.0.1:3001")
+	_, err = nextReload(t, w)
+	assert.EqualError(t, err, "WsFullEventTickerTime and WsLatestBlockEventTickerTime should be positive")
+	assert.Equal(t, 20*time.Second, SeeleConfig.ServerConfig.WebSocketConfig.WsFullEventTickerTime)
+	assert.Equal(t, 20*time.Second, WebSocketSettings().WsFullEventTickerTime)
+
+	// the pending restart of addr is not reported again on an unrelated change
+	writeTestConfig(t, dir, "info", ":8888", 20, "127.0.0.1:3001")
+	reload, err = nextReload(t, w)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ServerConfig.LogLevel"}, reload.Live)
+	assert.Empty(t, reload.Restart)
+	reload.Apply()
+
+	// but a new value is
+	writeTestConfig(t, dir, "info", ":7777", 20, "127.0.0.1:3001")
+	reload, err = nextReload(t, w)
+	assert.NoError(t, err)
+	assert.Empty(t, reload.Live)
+	assert.Equal(t, []string{"ServerConfig.Addr"}, reload.Restart)
+
+	// the servers are read from the monitor config file of the startup until a restart
+	otherFile := filepath.Join(dir, "other.json")
+	if err := ioutil.WriteFile(otherFile, []byte(`{"1": "127.0.0.1:3009"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	appConf := fmt.Sprintf("MonitorConfigFile = %v\nrun_mode = dev\n\n[dev]\naddr = :7777\nLogLevel = info\nEnableWebSocket = true\nWsFullEventTickerTime = 20\n", otherFile)
+	if err := ioutil.WriteFile(filepath.Join(dir, "app.conf"), []byte(appConf), 0644); err != nil {
+		t.Fatal(err)
+	}
+	modTime := time.Now().Add(time.Minute)
+	os.Chtimes(filepath.Join(dir, "app.conf"), modTime, modTime)
+	reload, err = nextReload(t, w)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"MonitorConfigFile"}, reload.Restart)
+	assert.False(t, reload.ServersChanged)
+	assert.Equal(t, []MonitorServer{{URL: "127.0.0.1:3001"}}, reload.Servers["1"])
+}
@@ -0,0 +1,150 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package config
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+)
+
+// MonitorServer is a monitor server of a shard in monitor.json
+type MonitorServer struct {
+	URL      string     `json:"url"`            // host:port, or with the ws, wss, http or https scheme
+	Priority int        `json:"priority"`       // the servers of a shard are tried by ascending priority
+	Pass     string     `json:"pass,omitempty"` // signs the hello instead of WsPass
+	TLS      *ServerTLS `json:"tls,omitempty"`  // tls of the wss and https urls
+}
+
+// ServerTLS is the tls config of a monitor server
+type ServerTLS struct {
+	CAFile             string `json:"caFile,omitempty"`   // pem of the CAs verifying the server, the system CAs if empty
+	CertFile           string `json:"certFile,omitempty"` // pem of the client certificate
+	KeyFile            string `json:"keyFile,omitempty"`  // pem of the client key
+	ServerName         string `json:"serverName,omitempty"`
+	InsecureSkipVerify bool   `json:"insecureSkipVerify,omitempty"`
+}
+
+// Config returns the tls.Config of the settings
+func (t *ServerTLS) Config() (*tls.Config, error) {
+	conf := &tls.Config{
+		ServerName:         t.ServerName,
+		InsecureSkipVerify: t.InsecureSkipVerify,
+	}
+	if t.CAFile != "" {
+		pem, err := ioutil.ReadFile(t.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.RootCAs = x509.NewCertPool()
+		if !conf.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificate in %v", t.CAFile)
+		}
+	}
+	if t.CertFile != "" || t.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(t.CertFile, t.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+	return conf, nil
+}
+
+// shardServers shard:<monitor servers by priority>, replaced on reload
+var shardServers = struct {
+	sync.RWMutex
+	byShard map[string][]MonitorServer
+}{}
+
+// SetMonitorServers replaces the monitor servers of the shards and ShardMap
+func SetMonitorServers(servers map[string][]MonitorServer) {
+	shardServers.Lock()
+	shardServers.byShard = servers
+	ShardMap = firstURLs(servers)
+	shardServers.Unlock()
+}
+
+// MonitorServers returns the monitor servers of the shard by priority
+func MonitorServers(shard string) []MonitorServer {
+	shardServers.RLock()
+	defer shardServers.RUnlock()
+	return shardServers.byShard[shard]
+}
+
+// ParseMonitorServers decodes monitor.json, each shard is a url, a server or
+// a list of urls and servers. The servers of a shard are sorted by priority,
+// the servers of the same priority keep their order.
+func ParseMonitorServers(data []byte) (map[string][]MonitorServer, error) {
+	var shards map[string]json.RawMessage
+	if err := json.Unmarshal(data, &shards); err != nil {
+		return nil, err
+	}
+	servers := make(map[string][]MonitorServer, len(shards))
+	for shard, raw := range shards {
+		var items []json.RawMessage
+		if bytes.HasPrefix(bytes.TrimSpace(raw), []byte("[")) {
+			if err := json.Unmarshal(raw, &items); err != nil {
+				return nil, fmt.Errorf("shard %v: %v", shard, err)
+			}
+		} else {
+			items = []json.RawMessage{raw}
+		}
+		list := make([]MonitorServer, 0, len(items))
+		for _, item := range items {
+			server, err := parseMonitorServer(item)
+			if err != nil {
+				return nil, fmt.Errorf("shard %v: %v", shard, err)
+			}
+			list = append(list, server)
+		}
+		if len(list) == 0 {
+			return nil, fmt.Errorf("shard %v: no monitor server", shard)
+		}
+		sort.SliceStable(list, func(i, j int) bool { return list[i].Priority < list[j].Priority })
+		servers[shard] = list
+	}
+	return servers, nil
+}
+
+// parseMonitorServer decodes a url or a server
+func parseMonitorServer(raw json.RawMessage) (MonitorServer, error) {
+	var server MonitorServer
+	if err := json.Unmarshal(raw, &server.URL); err != nil {
+		server = MonitorServer{}
+		if err = json.Unmarshal(raw, &server); err != nil {
+			return server, err
+		}
+	}
+	if server.URL == "" {
+		return server, errors.New("monitor server without url")
+	}
+	return server, nil
+}
+
+// GetMonitorServersFromFile reads the monitor servers from monitor.json
+func GetMonitorServersFromFile(filepath string) (map[string][]MonitorServer, error) {
+	buff, err := ioutil.ReadFile(filepath)
+	if err != nil {
+		return nil, err
+	}
+	return ParseMonitorServers(buff)
+}
+
+// firstURLs returns the url of the first server of every shard
+func firstURLs(servers map[string][]MonitorServer) map[string]string {
+	urls := make(map[string]string, len(servers))
+	for shard, list := range servers {
+		urls[shard] = list[0].URL
+	}
+	return urls
+}
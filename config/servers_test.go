@@ -0,0 +1,61 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMonitorServers(t *testing.T) {
+	// the flat form, one url per shard
+	servers, err := ParseMonitorServers([]byte(`{"1": "127.0.0.1:3000", "2": "127.0.0.1:3001"}`))
+	assert.NoError(t, err)
+	assert.Equal(t, map[string][]MonitorServer{
+		"1": {{URL: "127.0.0.1:3000"}},
+		"2": {{URL: "127.0.0.1:3001"}},
+	}, servers)
+
+	servers, err = ParseMonitorServers([]byte(`{
+		"1": [
+			{"url": "backup:3000", "priority": 2},
+			{"url": "wss://primary:3000", "priority": 1, "pass": "secret", "tls": {"caFile": "ca.pem", "serverName": "monitor"}},
+			"fallback:3000",
+			{"url": "backup2:3000", "priority": 2}
+		],
+		"2": {"url": "127.0.0.1:3001", "pass": "secret2"}
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, []MonitorServer{
+		{URL: "fallback:3000"},
+		{URL: "wss://primary:3000", Priority: 1, Pass: "secret", TLS: &ServerTLS{CAFile: "ca.pem", ServerName: "monitor"}},
+		{URL: "backup:3000", Priority: 2},
+		{URL: "backup2:3000", Priority: 2},
+	}, servers["1"])
+	assert.Equal(t, []MonitorServer{{URL: "127.0.0.1:3001", Pass: "secret2"}}, servers["2"])
+	assert.Equal(t, map[string]string{"1": "fallback:3000", "2": "127.0.0.1:3001"}, firstURLs(servers))
+
+	for data, expected := range map[string]string{
+		`{"1": []}`:                "shard 1: no monitor server",
+		`{"1": [{"priority": 1}]}`: "shard 1: monitor server without url",
+		`{"1": ""}`:                "shard 1: monitor server without url",
+		`{"1": [{"url": 1}]}`:      "shard 1: json: cannot unmarshal",
+		`{"1": "127.0.0.1:3000",}`: "invalid character",
+	} {
+		_, err = ParseMonitorServers([]byte(data))
+		if assert.Error(t, err, data) {
+			assert.Contains(t, err.Error(), expected, data)
+		}
+	}
+
+	_, err = (&ServerTLS{CAFile: "not-exist.pem"}).Config()
+	assert.Error(t, err)
+	conf, err := (&ServerTLS{ServerName: "monitor", InsecureSkipVerify: true}).Config()
+	assert.NoError(t, err)
+	assert.Equal(t, "monitor", conf.ServerName)
+	assert.True(t, conf.InsecureSkipVerify)
+}
@@ -0,0 +1,178 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nodesKey is the setting of the monitored nodes, assigned by assignConfig
+// as it reads the node sections
+const nodesKey = "nodes"
+
+// setting is a key of the run mode settings and its assignment into the
+// config, an invalid value is ignored
+type setting struct {
+	key    string // key in lower case
+	assign func(c *Config, value string)
+}
+
+// configSettings are the run mode settings, each overridden by its env, the
+// settings of a feature are assigned whether or not the feature is enabled
+var configSettings = []setting{
+	stringSetting("app_name", func(c *Config, v string) { c.AppName = v }),
+	stringSetting("servername", func(c *Config, v string) { c.ServerName = v }),
+	boolSetting("recoverpanic", func(c *Config, v bool) { c.RecoverPanic = v }),
+	stringSetting("monitorconfigfile", func(c *Config, v string) { c.MonitorConfigFile = v }),
+	stringSetting("tempfolder", func(c *Config, v string) { c.ServerConfig.EngineConfig.TempFolder = v }),
+
+	// server
+	stringSetting("addr", func(c *Config, v string) { c.ServerConfig.Addr = v }),
+	durationSetting("readtimeout", func(c *Config, v time.Duration) { c.ServerConfig.ReadTimeout = v }),
+	durationSetting("readheadertimeout", func(c *Config, v time.Duration) { c.ServerConfig.ReadHeaderTimeout = v }),
+	durationSetting("writetimeout", func(c *Config, v time.Duration) { c.ServerConfig.WriteTimeout = v }),
+	durationSetting("idletimeout", func(c *Config, v time.Duration) { c.ServerConfig.IdleTimeout = v }),
+	intSetting("maxheaderbytes", func(c *Config, v int) { c.ServerConfig.MaxHeaderBytes = v }),
+	stringSetting("loglevel", func(c *Config, v string) {
+		logLevel, ok := LogLevelMap[v]
+		if !ok {
+			logLevel = InfoLevel
+		}
+		c.ServerConfig.LogLevel = logLevel
+	}),
+	durationSetting("reloadinterval", func(c *Config, v time.Duration) {
+		if v >= 0 {
+			c.ServerConfig.ReloadInterval = v
+		}
+	}),
+	boolSetting("enablemetrics", func(c *Config, v bool) { c.ServerConfig.EnableMetrics = v }),
+
+	// aggregator
+	boolSetting("enableaggregator", func(c *Config, v bool) { c.ServerConfig.EnableAggregator = v }),
+	durationSetting("aggregatorofflinetimeout", func(c *Config, v time.Duration) { c.ServerConfig.AggregatorConfig.OfflineTimeout = v }),
+	durationSetting("aggregatorretention", func(c *Config, v time.Duration) { c.ServerConfig.AggregatorConfig.Retention = v }),
+	intSetting("aggregatormaxnodes", func(c *Config, v int) { c.ServerConfig.AggregatorConfig.MaxNodes = v }),
+
+	// web socket
+	boolSetting("enablewebsocket", func(c *Config, v bool) { c.ServerConfig.EnableWebSocket = v }),
+	stringSetting("wsurl", func(c *Config, v string) { c.ServerConfig.WebSocketConfig.WsURL = v }),
+	stringSetting("wsrouter", func(c *Config, v string) { c.ServerConfig.WebSocketConfig.WsRouter = v }),
+	stringSetting("wspass", func(c *Config, v string) { c.ServerConfig.WebSocketConfig.WsPass = v }),
+	durationSetting("wsfulleventtickertime", func(c *Config, v time.Duration) {
+		c.ServerConfig.WebSocketConfig.WsFullEventTickerTime = v
+	}),
+	durationSetting("wslatestblockeventtickertime", func(c *Config, v time.Duration) {
+		c.ServerConfig.WebSocketConfig.WsLatestBlockEventTickerTime = v
+	}),
+	durationSetting("delayreconntime", func(c *Config, v time.Duration) { c.ServerConfig.WebSocketConfig.DelayReConnTime = v }),
+	durationSetting("delaysendtime", func(c *Config, v time.Duration) { c.ServerConfig.WebSocketConfig.DelaySendTime = v }),
+	durationSetting("backoffmaxdelay", func(c *Config, v time.Duration) { c.ServerConfig.WebSocketConfig.BackoffMaxDelay = v }),
+	floatSetting("backoffmultiplier", func(c *Config, v float64) {
+		if v >= 1 {
+			c.ServerConfig.WebSocketConfig.BackoffMultiplier = v
+		}
+	}),
+	floatSetting("backoffjitter", func(c *Config, v float64) {
+		if v >= 0 && v <= 1 {
+			c.ServerConfig.WebSocketConfig.BackoffJitter = v
+		}
+	}),
+	durationSetting("backoffresetafter", func(c *Config, v time.Duration) { c.ServerConfig.WebSocketConfig.BackoffResetAfter = v }),
+	intSetting("reporterroraftertimes", func(c *Config, v int) { c.ServerConfig.WebSocketConfig.ReportErrorAfterTimes = v }),
+	intSetting("readyreportperiods", func(c *Config, v int) { c.ServerConfig.WebSocketConfig.ReadyReportPeriods = v }),
+	boolSetting("enablejournal", func(c *Config, v bool) { c.ServerConfig.WebSocketConfig.EnableJournal = v }),
+	intSetting("journalmaxentries", func(c *Config, v int) { c.ServerConfig.WebSocketConfig.JournalMaxEntries = v }),
+	{key: "journalmaxbytes", assign: func(c *Config, value string) {
+		if v, err := strconv.ParseInt(value, 10, 64); err == nil {
+			c.ServerConfig.WebSocketConfig.JournalMaxBytes = v
+		}
+	}},
+	durationSetting("journalmaxage", func(c *Config, v time.Duration) { c.ServerConfig.WebSocketConfig.JournalMaxAge = v }),
+	stringSetting("journaldroppolicy", func(c *Config, v string) {
+		if v = strings.ToLower(v); v == JournalDropOldest || v == JournalDropNewest {
+			c.ServerConfig.WebSocketConfig.JournalDropPolicy = v
+		}
+	}),
+
+	// rpc
+	boolSetting("enablerpc", func(c *Config, v bool) { c.ServerConfig.EnableRPC = v }),
+	stringSetting("rpcurl", func(c *Config, v string) { c.ServerConfig.RPCConfig.URL = v }),
+	durationSetting("rpctimeout", func(c *Config, v time.Duration) { c.ServerConfig.RPCConfig.Timeout = v }),
+	stringSetting("rpcscheme", func(c *Config, v string) { c.ServerConfig.RPCConfig.Scheme = strings.ToLower(v) }),
+	stringSetting("rpcusername", func(c *Config, v string) { c.ServerConfig.RPCConfig.Username = v }),
+	stringSetting("rpcpassword", func(c *Config, v string) { c.ServerConfig.RPCConfig.Password = v }),
+	stringSetting("rpcheaders", func(c *Config, v string) { c.ServerConfig.RPCConfig.Headers = parseHeaders(v) }),
+	stringSetting("rpccafile", func(c *Config, v string) { c.ServerConfig.RPCConfig.CAFile = v }),
+	intSetting("rpcpoolsize", func(c *Config, v int) { c.ServerConfig.RPCConfig.PoolSize = v }),
+	durationSetting("rpcidletimeout", func(c *Config, v time.Duration) { c.ServerConfig.RPCConfig.IdleTimeout = v }),
+	durationSetting("rpcfailbackinterval", func(c *Config, v time.Duration) { c.ServerConfig.RPCConfig.FailbackInterval = v }),
+	boolSetting("rpcdebug", func(c *Config, v bool) { c.ServerConfig.RPCConfig.Debug = v }),
+
+	// engine
+	intSetting("limitconnection", func(c *Config, v int) { c.ServerConfig.EngineConfig.LimitConnection = v }),
+	boolSetting("disableconsolecolor", func(c *Config, v bool) { c.ServerConfig.EngineConfig.DisableConsoleColor = v }),
+	boolSetting("writelog", func(c *Config, v bool) { c.ServerConfig.EngineConfig.WriteLog = v }),
+	stringSetting("logfile", func(c *Config, v string) { c.ServerConfig.EngineConfig.LogFile = v }),
+}
+
+// configKeys returns the keys of the run mode settings in lower case
+func configKeys() []string {
+	keys := make([]string, 0, len(configSettings)+1)
+	for _, s := range configSettings {
+		keys = append(keys, s.key)
+	}
+	return append(keys, nodesKey)
+}
+
+// assignSettings assigns the run mode settings into the config
+func assignSettings(c *Config, settings map[string]string) {
+	for _, s := range configSettings {
+		if value := settings[s.key]; value != "" {
+			s.assign(c, value)
+		}
+	}
+}
+
+func stringSetting(key string, set func(c *Config, v string)) setting {
+	return setting{key: key, assign: func(c *Config, value string) {
+		set(c, value)
+	}}
+}
+
+func boolSetting(key string, set func(c *Config, v bool)) setting {
+	return setting{key: key, assign: func(c *Config, value string) {
+		if v, err := strconv.ParseBool(value); err == nil {
+			set(c, v)
+		}
+	}}
+}
+
+func intSetting(key string, set func(c *Config, v int)) setting {
+	return setting{key: key, assign: func(c *Config, value string) {
+		if v, err := strconv.Atoi(value); err == nil {
+			set(c, v)
+		}
+	}}
+}
+
+func floatSetting(key string, set func(c *Config, v float64)) setting {
+	return setting{key: key, assign: func(c *Config, value string) {
+		if v, err := strconv.ParseFloat(value, 64); err == nil {
+			set(c, v)
+		}
+	}}
+}
+
+// durationSetting parses the value in DefaultTimeUnit
+func durationSetting(key string, set func(c *Config, v time.Duration)) setting {
+	return setting{key: key, assign: func(c *Config, value string) {
+		if v, err := time.ParseDuration(value + DefaultTimeUnit); err == nil {
+			set(c, v)
+		}
+	}}
+}
@@ -63,6 +63,16 @@ func NewConfig(adapterName, filename string) (Configure, error) {
 	return adapter.Parse(filename)
 }
 
+// NewConfigData adapterName is ini.
+// data is the config data.
+func NewConfigData(adapterName string, data []byte) (Configure, error) {
+	adapter, ok := adapters[adapterName]
+	if !ok {
+		return nil, fmt.Errorf("config: unknown adaptername %q (forgotten import?)", adapterName)
+	}
+	return adapter.ParseData(data)
+}
+
 // ExpandValueEnvForMap convert all string value with environment variable.
 func ExpandValueEnvForMap(m map[string]interface{}) map[string]interface{} {
 	for k, v := range m {
@@ -7,6 +7,8 @@ package config
 
 import (
 	"fmt"
+	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -38,3 +40,75 @@ func TestExpandValueEnv(t *testing.T) {
 	assert.NotEmptyf(t, yourShell, "your shell is: %v\n", yourShell)
 
 }
+
+func testSectionConfig(t *testing.T, adapterName string, data string) {
+	c, err := NewConfigData(adapterName, []byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "monitor-api", c.String("app_name"))
+	assert.Equal(t, ":9997", c.String("dev::Addr"))
+	assert.Equal(t, 10, c.DefaultInt("dev::WsFullEventTickerTime", 0))
+	assert.Equal(t, 0.2, c.DefaultFloat("dev::BackoffJitter", 0))
+	assert.True(t, c.DefaultBool("DEV::EnableWebSocket", false))
+	assert.Equal(t, []string{"alpha", "beta"}, c.Strings("dev::nodes"))
+	assert.Equal(t, "", c.String("dev::missing"))
+
+	section, err := c.GetSection("node.alpha")
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"rpcurl": "127.0.0.1:55027", "shard": "1"}, section)
+	_, err = c.GetSection("missing")
+	assert.Error(t, err)
+
+	// the saved file is parsed into the same config
+	assert.NoError(t, c.Set("dev::LogLevel", "info"))
+	file, err := ioutil.TempFile("", "monitor-api-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+	assert.NoError(t, c.SaveConfigFile(file.Name()))
+	saved, err := NewConfig(adapterName, file.Name())
+	assert.NoError(t, err)
+	assert.Equal(t, "info", saved.String("dev::loglevel"))
+	assert.Equal(t, "alpha;beta", saved.String("dev::nodes"))
+	assert.Equal(t, "monitor-api", saved.String("app_name"))
+}
+
+func TestJSONConfig(t *testing.T) {
+	testSectionConfig(t, "json", `{
+	"app_name": "monitor-api",
+	"dev": {
+		"Addr": ":9997",
+		"WsFullEventTickerTime": 10,
+		"BackoffJitter": 0.2,
+		"EnableWebSocket": true,
+		"Nodes": ["alpha", "beta"]
+	},
+	"node.alpha": {"RPCURL": "127.0.0.1:55027", "Shard": 1}
+}`)
+
+	_, err := NewConfigData("json", []byte(`{"dev": {"rpc": {"url": "127.0.0.1"}}}`))
+	assert.EqualError(t, err, "config: dev::rpc: objects are supported at the top level only")
+}
+
+func TestYAMLConfig(t *testing.T) {
+	testSectionConfig(t, "yaml", `
+app_name: monitor-api
+dev:
+  Addr: ":9997"
+  WsFullEventTickerTime: 10
+  BackoffJitter: 0.2
+  EnableWebSocket: true
+  Nodes:
+    - alpha
+    - beta
+node.alpha:
+  RPCURL: 127.0.0.1:55027
+  Shard: 1
+`)
+
+	_, err := NewConfigData("yaml", []byte("dev: [1, 2"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,59 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+)
+
+// JSONConfig implements Config to parse json file.
+type JSONConfig struct {
+}
+
+// Parse creates a new Config and parses the file configuration from the named file.
+func (js *JSONConfig) Parse(filename string) (Configure, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return js.ParseData(data)
+}
+
+// ParseData parse the json data, the top level objects are the sections
+func (js *JSONConfig) ParseData(data []byte) (Configure, error) {
+	var doc map[string]interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	cfg, err := newSectionContainer(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONConfigContainer{cfg}, nil
+}
+
+// JSONConfigContainer A Config represents the json configuration.
+// When set and get value, support key as section::name type.
+type JSONConfigContainer struct {
+	*IniConfigContainer
+}
+
+// SaveConfigFile save the config into file.
+func (c *JSONConfigContainer) SaveConfigFile(filename string) error {
+	data, err := json.MarshalIndent(c.document(), "", "    ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, append(data, '\n'), 0644)
+}
+
+func init() {
+	Register("json", &JSONConfig{})
+}
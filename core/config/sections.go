@@ -0,0 +1,122 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// newSectionContainer converts a decoded json or yaml document into the
+// sections of an ini container, so the lookups behave the same whatever the
+// format. The values at the top level go into the default section, the
+// objects at the top level are the sections.
+func newSectionContainer(doc map[string]interface{}) (*IniConfigContainer, error) {
+	cfg := &IniConfigContainer{
+		data:           map[string]map[string]string{defaultSection: {}},
+		sectionComment: make(map[string]string),
+		keyComment:     make(map[string]string),
+		RWMutex:        sync.RWMutex{},
+	}
+	for key, value := range doc {
+		section, ok := toStringMap(value)
+		if !ok {
+			v, err := sectionValue(key, value)
+			if err != nil {
+				return nil, err
+			}
+			cfg.data[defaultSection][strings.ToLower(key)] = v
+			continue
+		}
+
+		name := strings.ToLower(key) // section name case insensitive
+		if _, ok := cfg.data[name]; !ok {
+			cfg.data[name] = make(map[string]string)
+		}
+		for k, value := range section {
+			v, err := sectionValue(key+"::"+k, value)
+			if err != nil {
+				return nil, err
+			}
+			cfg.data[name][strings.ToLower(k)] = v
+		}
+	}
+	return cfg, nil
+}
+
+// toStringMap returns the object of json or yaml with string keys
+func toStringMap(value interface{}) (map[string]interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return v, true
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, value := range v {
+			m[fmt.Sprint(k)] = value
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+// sectionValue returns the value as in an ini file, the lists are joined
+// with ';' for Strings
+func sectionValue(key string, value interface{}) (string, error) {
+	switch v := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return ExpandValueEnv(v), nil
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64), nil
+	case []interface{}:
+		items := make([]string, 0, len(v))
+		for _, item := range v {
+			if _, ok := item.([]interface{}); ok {
+				return "", fmt.Errorf("config: %v: nested lists are not supported", key)
+			}
+			s, err := sectionValue(key, item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ";"), nil
+	}
+	if _, ok := toStringMap(value); ok {
+		return "", fmt.Errorf("config: %v: objects are supported at the top level only", key)
+	}
+	return fmt.Sprint(value), nil
+}
+
+// document returns the sections as a json or yaml document, the default
+// section at the top level
+func (c *IniConfigContainer) document() map[string]interface{} {
+	c.RLock()
+	defer c.RUnlock()
+	doc := make(map[string]interface{}, len(c.data))
+	for key, value := range c.data[defaultSection] {
+		doc[key] = value
+	}
+	names := make([]string, 0, len(c.data))
+	for name := range c.data {
+		if name != defaultSection {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		section := make(map[string]string, len(c.data[name]))
+		for key, value := range c.data[name] {
+			section[key] = value
+		}
+		doc[name] = section
+	}
+	return doc
+}
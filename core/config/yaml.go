@@ -0,0 +1,57 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package config
+
+import (
+	"io/ioutil"
+
+	"gopkg.in/yaml.v2"
+)
+
+// YAMLConfig implements Config to parse yaml file.
+type YAMLConfig struct {
+}
+
+// Parse creates a new Config and parses the file configuration from the named file.
+func (yml *YAMLConfig) Parse(filename string) (Configure, error) {
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	return yml.ParseData(data)
+}
+
+// ParseData parse the yaml data, the top level mappings are the sections
+func (yml *YAMLConfig) ParseData(data []byte) (Configure, error) {
+	var doc map[string]interface{}
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	cfg, err := newSectionContainer(doc)
+	if err != nil {
+		return nil, err
+	}
+	return &YAMLConfigContainer{cfg}, nil
+}
+
+// YAMLConfigContainer A Config represents the yaml configuration.
+// When set and get value, support key as section::name type.
+type YAMLConfigContainer struct {
+	*IniConfigContainer
+}
+
+// SaveConfigFile save the config into file.
+func (c *YAMLConfigContainer) SaveConfigFile(filename string) error {
+	data, err := yaml.Marshal(c.document())
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filename, data, 0644)
+}
+
+func init() {
+	Register("yaml", &YAMLConfig{})
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
 
+	"github.com/seeleteam/monitor-api/api/handlers"
 	"github.com/seeleteam/monitor-api/api/routers"
 	"github.com/seeleteam/monitor-api/core/logs"
 )
@@ -73,5 +74,5 @@ func (config *EngineConfig) Init() http.Handler {
 
 	// here init the routers, need refactor
 	routers.InitRouters(e)
-	return e
+	return handlers.WithResponseWriter(e)
 }
@@ -0,0 +1,45 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package logs
+
+import (
+	"github.com/sirupsen/logrus"
+)
+
+// Entry wrapper logrus.Entry, logs with the fields attached
+type Entry struct {
+	entry *logrus.Entry
+}
+
+// WithFields create the Entry with the fields on the default logger
+func WithFields(fields logrus.Fields) *Entry {
+	return &Entry{entry: logs.WithFields(fields)}
+}
+
+// Debug wrapper Debug logger with fields
+func (e *Entry) Debug(f interface{}, args ...interface{}) {
+	e.entry.Debug(formatLog(f, args...))
+}
+
+// Info wrapper Info logger with fields
+func (e *Entry) Info(f interface{}, args ...interface{}) {
+	e.entry.Info(formatLog(f, args...))
+}
+
+// Warn wrapper Warn logger with fields
+func (e *Entry) Warn(f interface{}, args ...interface{}) {
+	e.entry.Warn(formatLog(f, args...))
+}
+
+// Error wrapper Error logger with fields
+func (e *Entry) Error(f interface{}, args ...interface{}) {
+	e.entry.Error(formatLog(f, args...))
+}
+
+// Fatal wrapper Fatal logger with fields
+func (e *Entry) Fatal(f interface{}, args ...interface{}) {
+	e.entry.Fatal(formatLog(f, args...))
+}
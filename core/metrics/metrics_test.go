@@ -0,0 +1,55 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package metrics
+
+import (
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistryWriteTo(t *testing.T) {
+	r := NewRegistry()
+	peers := r.NewGaugeVec("node_peers", "Peers of the node.", "node", "shard")
+	emits := r.NewCounterVec("emits_total", "Emits sent.", "emit")
+	latency := r.NewHistogramVec("call_seconds", "Call latency.", []float64{0.1, 1}, "method")
+
+	peers.Set(3, "alpha", "1")
+	peers.Set(5, `be"ta`, "2")
+	emits.Inc("stats")
+	emits.Add(2, "stats")
+	emits.Add(-1, "stats")
+	latency.Observe(0.05, "seele_getInfo")
+	latency.Observe(0.5, "seele_getInfo")
+	latency.Observe(2, "seele_getInfo")
+
+	recorder := httptest.NewRecorder()
+	r.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.Equal(t, ContentType, recorder.Header().Get("Content-Type"))
+	assert.Equal(t, `# HELP node_peers Peers of the node.
+# TYPE node_peers gauge
+node_peers{node="alpha",shard="1"} 3
+node_peers{node="be\"ta",shard="2"} 5
+# HELP emits_total Emits sent.
+# TYPE emits_total counter
+emits_total{emit="stats"} 3
+# HELP call_seconds Call latency.
+# TYPE call_seconds histogram
+call_seconds_bucket{method="seele_getInfo",le="0.1"} 1
+call_seconds_bucket{method="seele_getInfo",le="1"} 2
+call_seconds_bucket{method="seele_getInfo",le="+Inf"} 3
+call_seconds_sum{method="seele_getInfo"} 2.55
+call_seconds_count{method="seele_getInfo"} 3
+`, recorder.Body.String())
+
+	peers.DeleteLabel("node", "alpha")
+	recorder = httptest.NewRecorder()
+	r.Handler().ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	assert.NotContains(t, recorder.Body.String(), "alpha")
+	assert.Panics(t, func() { peers.Set(1, "alpha") })
+	assert.Panics(t, func() { r.NewGaugeVec("node_peers", "again") })
+}
@@ -6,8 +6,11 @@
 package core
 
 import (
+	"context"
+	"net"
 	"net/http"
 	"os"
+	"time"
 
 	"golang.org/x/sync/errgroup"
 
@@ -16,7 +19,7 @@ import (
 )
 
 const (
-	defaultHammerTime = 10 // if use graceful shutdown, this will effect
+	defaultHammerTime = 10 * time.Second // max time of the graceful shutdown, then the connections are closed
 )
 
 // MonitorServer monitor server config
@@ -92,27 +95,41 @@ func GetServer(g *errgroup.Group, handlerConfig ...*EngineConfig) (slServer *Mon
 	}
 
 	return &MonitorServer{
-		Server: &http.Server{
+		Server: cancelOnShutdown(&http.Server{
 			Addr:           currentServerConfig.Addr,
 			Handler:        currentEngineConfig.Init(),
 			ReadTimeout:    currentServerConfig.ReadTimeout,
 			WriteTimeout:   currentServerConfig.WriteTimeout,
 			IdleTimeout:    currentServerConfig.IdleTimeout,
 			MaxHeaderBytes: currentServerConfig.MaxHeaderBytes,
-		},
+		}),
 		G: g,
 	}
 }
 
+// cancelOnShutdown cancels the context of the requests when the server shuts
+// down, so the long-lived requests like the event streams end instead of
+// holding the shutdown until the hammer time. A server with its own
+// BaseContext is kept.
+func cancelOnShutdown(server *http.Server) *http.Server {
+	if server.BaseContext != nil {
+		return server
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	server.BaseContext = func(net.Listener) context.Context { return ctx }
+	server.RegisterOnShutdown(cancel)
+	return server
+}
+
 // NewServer create new MonitorServer with http.Server and errgroup.Group
 func (sl *MonitorServer) NewServer(server *http.Server, g *errgroup.Group) {
-	sl.Server = server
+	sl.Server = cancelOnShutdown(server)
 	sl.G = g
 }
 
 // NewServerTLS create new SSL MonitorServer with http.Server, errgroup.Group and TLS config file
 func (sl *MonitorServer) NewServerTLS(server *http.Server, certFile string, keyFile string, g *errgroup.Group) {
-	sl.Server = server
+	sl.Server = cancelOnShutdown(server)
 	sl.CertFile = certFile
 	sl.KeyFile = keyFile
 	sl.G = g
@@ -121,13 +138,34 @@ func (sl *MonitorServer) NewServerTLS(server *http.Server, certFile string, keyF
 // RunServer run our server in a goroutine so that it doesn't block.
 func (sl *MonitorServer) RunServer() {
 	sl.G.Go(func() error {
-		return http.ListenAndServe(sl.Server.Addr, sl.Server.Handler)
+		return ignoreServerClosed(sl.Server.ListenAndServe())
 	})
 }
 
 // RunServerTLS run our server with tls in a goroutine so that it doesn't block.
 func (sl *MonitorServer) RunServerTLS() {
 	sl.G.Go(func() error {
-		return http.ListenAndServeTLS(sl.Server.Addr, sl.CertFile, sl.KeyFile, sl.Server.Handler)
+		return ignoreServerClosed(sl.Server.ListenAndServeTLS(sl.CertFile, sl.KeyFile))
 	})
 }
+
+// ignoreServerClosed drops the error returned after Shutdown
+func ignoreServerClosed(err error) error {
+	if err == http.ErrServerClosed {
+		return nil
+	}
+	return err
+}
+
+// Shutdown stops the server gracefully, the requests in flight have the
+// hammer time to finish before the connections are closed.
+func (sl *MonitorServer) Shutdown(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, defaultHammerTime)
+	defer cancel()
+	err := sl.Server.Shutdown(ctx)
+	if err == context.DeadlineExceeded {
+		logs.Warn("http server not stopped within %v, close the connections", defaultHammerTime)
+		return sl.Server.Close()
+	}
+	return err
+}
@@ -0,0 +1,41 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package core
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShutdownCancelsStreams(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	started := make(chan struct{})
+	server := cancelOnShutdown(&http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+		close(started)
+		// a stream ends only when the request is done
+		<-r.Context().Done()
+	})})
+	go server.Serve(listener)
+
+	go http.Get("http://" + listener.Addr().String())
+	<-started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	start := time.Now()
+	assert.NoError(t, server.Shutdown(ctx))
+	assert.True(t, time.Since(start) < 5*time.Second)
+}
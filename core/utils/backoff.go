@@ -0,0 +1,69 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package utils
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Backoff returns exponentially growing delays with jitter, so the clients
+// failing at the same time do not retry in lockstep. It is not safe for
+// concurrent use.
+type Backoff struct {
+	Initial    time.Duration // delay of the first attempt
+	Max        time.Duration // max delay before the jitter, 0 means no max
+	Multiplier float64       // growth of the delay per attempt, less than 1 means 1
+	Jitter     float64       // the delay is randomized within ±Jitter of itself, in [0, 1]
+
+	attempt int
+	rand    *rand.Rand
+}
+
+// NewBackoff create a backoff starting at initial
+func NewBackoff(initial, max time.Duration, multiplier, jitter float64) *Backoff {
+	return &Backoff{
+		Initial:    initial,
+		Max:        max,
+		Multiplier: multiplier,
+		Jitter:     jitter,
+		rand:       rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Next returns the delay of the next attempt
+func (b *Backoff) Next() time.Duration {
+	multiplier := b.Multiplier
+	if multiplier < 1 {
+		multiplier = 1
+	}
+	delay := float64(b.Initial) * math.Pow(multiplier, float64(b.attempt))
+	if b.Max > 0 && delay > float64(b.Max) {
+		delay = float64(b.Max)
+	}
+	// the jitter at most doubles the delay, keep it in a time.Duration
+	if delay > math.MaxInt64/2 {
+		delay = math.MaxInt64 / 2
+	}
+	b.attempt++
+
+	jitter := math.Min(math.Max(b.Jitter, 0), 1)
+	if jitter > 0 && b.rand != nil {
+		delay *= 1 - jitter + 2*jitter*b.rand.Float64()
+	}
+	return time.Duration(delay)
+}
+
+// Attempt returns the attempts since the last reset
+func (b *Backoff) Attempt() int {
+	return b.attempt
+}
+
+// Reset starts again from the initial delay
+func (b *Backoff) Reset() {
+	b.attempt = 0
+}
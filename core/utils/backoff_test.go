@@ -0,0 +1,54 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package utils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBackoff(t *testing.T) {
+	b := NewBackoff(time.Second, 10*time.Second, 2, 0)
+	var delays []time.Duration
+	for i := 0; i < 6; i++ {
+		delays = append(delays, b.Next())
+	}
+	assert.Equal(t, []time.Duration{
+		time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 10 * time.Second, 10 * time.Second,
+	}, delays)
+	assert.Equal(t, 6, b.Attempt())
+
+	b.Reset()
+	assert.Equal(t, 0, b.Attempt())
+	assert.Equal(t, time.Second, b.Next())
+
+	// no max keeps growing without overflow
+	b = NewBackoff(time.Second, 0, 10, 0)
+	for i := 0; i < 100; i++ {
+		assert.True(t, b.Next() > 0)
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+	b := NewBackoff(time.Second, time.Minute, 2, 0.5)
+	seen := make(map[time.Duration]bool)
+	for i := 0; i < 50; i++ {
+		delay := b.Next()
+		assert.True(t, delay >= 500*time.Millisecond && delay <= 1500*time.Millisecond, delay.String())
+		seen[delay] = true
+		b.Reset()
+	}
+	assert.True(t, len(seen) > 1)
+
+	// the jitter is applied after the max
+	for i := 0; i < 10; i++ {
+		b.Next()
+	}
+	delay := b.Next()
+	assert.True(t, delay >= 30*time.Second && delay <= 90*time.Second, delay.String())
+}
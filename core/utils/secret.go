@@ -0,0 +1,38 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+	"time"
+)
+
+// SignSecret returns the hex HMAC-SHA256 over the node id and timestamp(ms),
+// keyed with the pass, so the pass itself never goes over the wire.
+func SignSecret(pass, id string, timestamp int64) string {
+	mac := hmac.New(sha256.New, []byte(pass))
+	mac.Write([]byte(id))
+	mac.Write([]byte(":"))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifySecret reports whether the secret is signed with the pass for the id,
+// and the timestamp(ms) is within maxSkew of now.
+func VerifySecret(pass, id string, timestamp int64, secret string, maxSkew time.Duration) bool {
+	skew := time.Since(time.Unix(0, timestamp*int64(time.Millisecond)))
+	if skew < 0 {
+		skew = -skew
+	}
+	if skew > maxSkew {
+		return false
+	}
+	expected := SignSecret(pass, id, timestamp)
+	return hmac.Equal([]byte(expected), []byte(secret))
+}
@@ -0,0 +1,344 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package protocol
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+)
+
+// Header identifies the node of the payloads sent by the agents
+type Header struct {
+	ID              string `json:"id"`
+	NetVersion      uint64 `json:"netVersion"`
+	Shard           uint   `json:"shard"`
+	ProtocolVersion int    `json:"protocolVersion,omitempty"`
+}
+
+// NewHeader returns the header of the node with the current protocol version
+func NewHeader(id string, netVersion uint64, shard uint) Header {
+	return Header{ID: id, NetVersion: netVersion, Shard: shard, ProtocolVersion: Version}
+}
+
+// Validate checks the node id and the protocol version
+func (h *Header) Validate() error {
+	if h.ID == "" {
+		return errors.New("missing id")
+	}
+	if h.ProtocolVersion > Version {
+		return fmt.Errorf("unsupported protocol version %v, max %v", h.ProtocolVersion, Version)
+	}
+	return nil
+}
+
+// NodeInfo is the collection of metainformation about a node that is
+// displayed on the monitoring page.
+type NodeInfo struct {
+	Name        string `json:"name"`
+	Node        string `json:"node"`
+	Port        int    `json:"port"` // the monitor api client port, can overwrite use monitor api client api port
+	Protocol    string `json:"protocol"`
+	API         string `json:"api"`
+	Os          string `json:"os"`
+	OsVer       string `json:"os_v"`
+	Client      string `json:"client"`
+	NodeVersion string `json:"nodeVersion"` // the monitor api client version
+	NetVersion  uint64 `json:"netVersion"`
+	Shard       uint   `json:"shard"`
+	History     bool   `json:"canUpdateHistory"` // the agent answers history requests
+}
+
+// NodeStats is the information about the local node
+type NodeStats struct {
+	Active   bool   `json:"active"`
+	Syncing  bool   `json:"syncing"`
+	Mining   bool   `json:"mining"`
+	Hashrate uint64 `json:"hashrate"`
+	Peers    int    `json:"peers"`
+}
+
+// Block is the information about a block
+type Block struct {
+	HeadHash   string   `json:"headHash"`
+	Height     uint64   `json:"height"`
+	Timestamp  *big.Int `json:"timestamp"`
+	Difficulty *big.Int `json:"difficulty"`
+	Miner      string   `json:"miner"`
+	TxCount    int      `json:"txcount"`
+}
+
+// Validate checks the block hash
+func (b *Block) Validate() error {
+	if b.HeadHash == "" {
+		return errors.New("missing block headHash")
+	}
+	return nil
+}
+
+// Hello is the payload of the hello emit, sent first after connecting. It is
+// signed with timestamp and secret if the monitor server has a password.
+type Hello struct {
+	Header
+	Info      *NodeInfo  `json:"info"`
+	Block     *Block     `json:"block"`
+	Stats     *NodeStats `json:"stats"`
+	Latency   string     `json:"latency"`
+	Timestamp int64      `json:"timestamp,omitempty"`
+	Secret    string     `json:"secret,omitempty"`
+}
+
+// Validate checks the header and the node info
+func (h *Hello) Validate() error {
+	if err := h.Header.Validate(); err != nil {
+		return err
+	}
+	if h.Info == nil {
+		return errors.New("missing info")
+	}
+	if h.Block != nil {
+		return h.Block.Validate()
+	}
+	return nil
+}
+
+// NodeInfoReport is the payload of the nodeInfo emit
+type NodeInfoReport struct {
+	Header
+	Info *NodeInfo `json:"info"`
+}
+
+// Validate checks the header and the node info
+func (r *NodeInfoReport) Validate() error {
+	if err := r.Header.Validate(); err != nil {
+		return err
+	}
+	if r.Info == nil {
+		return errors.New("missing info")
+	}
+	return nil
+}
+
+// StatsReport is the payload of the stats emit
+type StatsReport struct {
+	Header
+	Stats *NodeStats `json:"stats"`
+}
+
+// Validate checks the header and the stats
+func (r *StatsReport) Validate() error {
+	if err := r.Header.Validate(); err != nil {
+		return err
+	}
+	if r.Stats == nil {
+		return errors.New("missing stats")
+	}
+	return nil
+}
+
+// BlockReport is the payload of the block emit
+type BlockReport struct {
+	Header
+	Block *Block `json:"block"`
+}
+
+// Validate checks the header and the block
+func (r *BlockReport) Validate() error {
+	if err := r.Header.Validate(); err != nil {
+		return err
+	}
+	if r.Block == nil {
+		return errors.New("missing block")
+	}
+	return r.Block.Validate()
+}
+
+// LatencyReport is the payload of the latency emit, the latency is in
+// milliseconds with one decimal
+type LatencyReport struct {
+	Header
+	Latency string `json:"latency"`
+}
+
+// Validate checks the header and the latency
+func (r *LatencyReport) Validate() error {
+	if err := r.Header.Validate(); err != nil {
+		return err
+	}
+	if r.Latency == "" {
+		return errors.New("missing latency")
+	}
+	return nil
+}
+
+// HistoryRequest is the payload of the history emit sent by the monitor
+// server, either a list of heights or the range [min, max].
+type HistoryRequest struct {
+	List []uint64 `json:"list"`
+	Min  *uint64  `json:"min"`
+	Max  *uint64  `json:"max"`
+}
+
+// Validate checks the list or the range is set, the heights fit the int64 of the rpc
+func (r *HistoryRequest) Validate() error {
+	if len(r.List) > 0 {
+		for _, height := range r.List {
+			if height > math.MaxInt64 {
+				return fmt.Errorf("history request height %v out of range", height)
+			}
+		}
+		return nil
+	}
+	if r.Min == nil || r.Max == nil {
+		return errors.New("history request should have list or min and max")
+	}
+	if *r.Min > *r.Max {
+		return fmt.Errorf("history request min %v > max %v", *r.Min, *r.Max)
+	}
+	if *r.Max > math.MaxInt64 {
+		return fmt.Errorf("history request max %v out of range", *r.Max)
+	}
+	return nil
+}
+
+// HistoryReport is the payload of the history emit sent by the agent, one
+// chunk of the blocks requested
+type HistoryReport struct {
+	Header
+	History []*Block `json:"history"`
+	Missing []uint64 `json:"missing,omitempty"` // heights of the chunk the node failed to return
+	Chunk   int      `json:"chunk"`
+	Chunks  int      `json:"chunks"`
+}
+
+// IsHistoryReport reports whether the history message is a report of an
+// agent rather than a request of the monitor server
+func IsHistoryReport(msg *Message) bool {
+	if msg.Event != EventHistory {
+		return false
+	}
+	var report struct {
+		Chunks *int `json:"chunks"`
+	}
+	return json.Unmarshal(msg.Payload, &report) == nil && report.Chunks != nil
+}
+
+// Validate checks the header and the chunk
+func (r *HistoryReport) Validate() error {
+	if err := r.Header.Validate(); err != nil {
+		return err
+	}
+	if r.Chunk < 1 || r.Chunk > r.Chunks {
+		return fmt.Errorf("invalid history chunk %v/%v", r.Chunk, r.Chunks)
+	}
+	return nil
+}
+
+// Reorg describes a chain reorganization
+type Reorg struct {
+	Depth      uint64 `json:"depth"`
+	ForkHeight uint64 `json:"forkHeight"`
+	Height     uint64 `json:"height"`
+	OldHash    string `json:"oldHash"`
+	NewHash    string `json:"newHash"`
+}
+
+// ReorgReport is the payload of the reorg emit
+type ReorgReport struct {
+	Header
+	Reorg *Reorg `json:"reorg"`
+}
+
+// Validate checks the header and the reorg
+func (r *ReorgReport) Validate() error {
+	if err := r.Header.Validate(); err != nil {
+		return err
+	}
+	if r.Reorg == nil {
+		return errors.New("missing reorg")
+	}
+	return nil
+}
+
+// Ping is the payload of the node-ping emit, the client time is in unix milliseconds
+type Ping struct {
+	Header
+	ClientTime int64 `json:"clientTime"`
+}
+
+// Pong is the payload of the node-pong emit
+type Pong struct {
+	ID         string `json:"id"`
+	ClientTime string `json:"clientTime"`
+}
+
+// MinInterval is the shortest report period a set-interval may ask for
+const MinInterval = 1000
+
+// SetInterval is the payload of the set-interval emit, the periods of the
+// full and the block reports in milliseconds, 0 keeps the period
+type SetInterval struct {
+	Full  uint64 `json:"full"`
+	Block uint64 `json:"block"`
+}
+
+// Validate checks a period is set and none is below MinInterval
+func (r *SetInterval) Validate() error {
+	if r.Full == 0 && r.Block == 0 {
+		return errors.New("set-interval should have full or block")
+	}
+	if (r.Full != 0 && r.Full < MinInterval) || (r.Block != 0 && r.Block < MinInterval) {
+		return fmt.Errorf("set-interval periods should be at least %vms", MinInterval)
+	}
+	return nil
+}
+
+// CommandError is the payload of the error emit, the answer of a command of
+// the monitor server that failed or is unknown
+type CommandError struct {
+	Header
+	Command string `json:"command"` // empty if the message could not be parsed
+	Error   string `json:"error"`
+}
+
+// Validate checks the header and the error
+func (r *CommandError) Validate() error {
+	if err := r.Header.Validate(); err != nil {
+		return err
+	}
+	if r.Error == "" {
+		return errors.New("missing error")
+	}
+	return nil
+}
+
+// ShardChange is the payload of the shard-change emit, the header has the
+// shard the node left
+type ShardChange struct {
+	Header
+	From uint `json:"from"`
+	To   uint `json:"to"`
+}
+
+// Validate checks the header and the shards differ
+func (r *ShardChange) Validate() error {
+	if err := r.Header.Validate(); err != nil {
+		return err
+	}
+	if r.From == r.To {
+		return fmt.Errorf("shard-change to the same shard %v", r.To)
+	}
+	return nil
+}
+
+// Goodbye is the payload of the goodbye emit, the last emit of a connection
+// the agent closes
+type Goodbye struct {
+	Header
+	Reason string `json:"reason"`
+}
@@ -0,0 +1,186 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+// Package protocol defines the emits exchanged between the agents and the
+// monitor server, {"emit": [event, payload]}, with typed payloads.
+package protocol
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// Version is the protocol version sent in the header of the payloads, the
+// payloads without version come from the agents before it was introduced.
+const Version = 1
+
+// the events of the emits
+const (
+	EventHello    = "hello"     // agent: node info, stats, block and latency after connecting
+	EventNodeInfo = "nodeInfo"  // agent: node info
+	EventStats    = "stats"     // agent: node stats
+	EventBlock    = "block"     // agent: current block
+	EventLatency  = "latency"   // agent: measured latency
+	EventHistory  = "history"   // agent: history blocks, server: history request
+	EventReorg    = "reorg"     // agent: chain reorganization
+	EventNodePing = "node-ping" // agent: latency measurement request
+	EventNodePong = "node-pong" // server: answer of node-ping
+
+	EventStatsRequest = "stats-request" // server: ask for a full report now
+	EventReady        = "ready"         // server: the hello is accepted, start reporting
+	EventSetInterval  = "set-interval"  // server: change the report periods
+	EventError        = "error"         // agent: a server command failed or is unknown
+
+	EventShardChange = "shard-change" // agent: the node moved to another shard, sent to the old one
+	EventGoodbye     = "goodbye"      // agent: the connection is closed on purpose
+)
+
+// Message is an emit, the payload is kept encoded until decoded by Decode
+type Message struct {
+	Event   string
+	Payload json.RawMessage
+}
+
+// envelope is the wire form of the message
+type envelope struct {
+	Emit []json.RawMessage `json:"emit"`
+}
+
+// validator is implemented by the payloads checking their fields
+type validator interface {
+	Validate() error
+}
+
+// NewMessage validates and encodes the payload of the event
+func NewMessage(event string, payload interface{}) (*Message, error) {
+	if event == "" {
+		return nil, errors.New("protocol: empty event")
+	}
+	if v, ok := payload.(validator); ok {
+		if err := v.Validate(); err != nil {
+			return nil, fmt.Errorf("protocol: invalid %v payload: %v", event, err)
+		}
+	}
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("protocol: encode %v payload: %v", event, err)
+	}
+	return &Message{Event: event, Payload: raw}, nil
+}
+
+// Encode returns the wire form of the emit of the payload
+func Encode(event string, payload interface{}) ([]byte, error) {
+	msg, err := NewMessage(event, payload)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(msg)
+}
+
+// Parse decodes the wire form of an emit, the payload is decoded by Decode
+func Parse(data []byte) (*Message, error) {
+	msg := &Message{}
+	if err := json.Unmarshal(data, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// MarshalJSON encodes the message as {"emit": [event, payload]}
+func (m *Message) MarshalJSON() ([]byte, error) {
+	event, err := json.Marshal(m.Event)
+	if err != nil {
+		return nil, err
+	}
+	emit := []json.RawMessage{event}
+	if len(m.Payload) != 0 {
+		emit = append(emit, m.Payload)
+	}
+	return json.Marshal(&envelope{Emit: emit})
+}
+
+// UnmarshalJSON decodes {"emit": [event, payload]}, the payload is optional
+func (m *Message) UnmarshalJSON(data []byte) error {
+	var e envelope
+	if err := json.Unmarshal(data, &e); err != nil {
+		return fmt.Errorf("protocol: invalid message: %v", err)
+	}
+	if len(e.Emit) == 0 || len(e.Emit) > 2 {
+		return fmt.Errorf("protocol: invalid message: emit should be [event, payload], got %v items", len(e.Emit))
+	}
+	var event string
+	if err := json.Unmarshal(e.Emit[0], &event); err != nil || event == "" {
+		return fmt.Errorf("protocol: invalid message: invalid event %s", e.Emit[0])
+	}
+	m.Event = event
+	m.Payload = nil
+	if len(e.Emit) == 2 {
+		m.Payload = e.Emit[1]
+	}
+	return nil
+}
+
+// Decode decodes and validates the payload into v
+func (m *Message) Decode(v interface{}) error {
+	if len(m.Payload) == 0 || bytes.Equal(bytes.TrimSpace(m.Payload), []byte("null")) {
+		return fmt.Errorf("protocol: %v without payload", m.Event)
+	}
+	if err := json.Unmarshal(m.Payload, v); err != nil {
+		return fmt.Errorf("protocol: decode %v payload: %v", m.Event, err)
+	}
+	if val, ok := v.(validator); ok {
+		if err := val.Validate(); err != nil {
+			return fmt.Errorf("protocol: invalid %v payload: %v", m.Event, err)
+		}
+	}
+	return nil
+}
+
+// NewPayload returns an empty payload of the event to Decode into
+func NewPayload(event string) (interface{}, error) {
+	switch event {
+	case EventHello:
+		return &Hello{}, nil
+	case EventNodeInfo:
+		return &NodeInfoReport{}, nil
+	case EventStats:
+		return &StatsReport{}, nil
+	case EventBlock:
+		return &BlockReport{}, nil
+	case EventLatency:
+		return &LatencyReport{}, nil
+	case EventHistory:
+		return &HistoryReport{}, nil
+	case EventReorg:
+		return &ReorgReport{}, nil
+	case EventNodePing:
+		return &Ping{}, nil
+	case EventNodePong:
+		return &Pong{}, nil
+	case EventSetInterval:
+		return &SetInterval{}, nil
+	case EventError:
+		return &CommandError{}, nil
+	case EventShardChange:
+		return &ShardChange{}, nil
+	case EventGoodbye:
+		return &Goodbye{}, nil
+	}
+	return nil, fmt.Errorf("protocol: unknown event %v", event)
+}
+
+// DecodePayload decodes the payload into the type of the event
+func (m *Message) DecodePayload() (interface{}, error) {
+	payload, err := NewPayload(m.Event)
+	if err != nil {
+		return nil, err
+	}
+	if err = m.Decode(payload); err != nil {
+		return nil, err
+	}
+	return payload, nil
+}
@@ -0,0 +1,82 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package protocol
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncodeDecode(t *testing.T) {
+	data, err := Encode(EventBlock, &BlockReport{
+		Header: NewHeader("node1", 1, 2),
+		Block:  &Block{HeadHash: "0x1", Height: 10, Timestamp: big.NewInt(100), Difficulty: big.NewInt(5)},
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, `{"emit":["block",{"id":"node1","netVersion":1,"shard":2,"protocolVersion":1,`+
+		`"block":{"headHash":"0x1","height":10,"timestamp":100,"difficulty":5,"miner":"","txcount":0}}]}`, string(data))
+
+	msg, err := Parse(data)
+	assert.NoError(t, err)
+	assert.Equal(t, EventBlock, msg.Event)
+	payload, err := msg.DecodePayload()
+	assert.NoError(t, err)
+	report := payload.(*BlockReport)
+	assert.Equal(t, "node1", report.ID)
+	assert.Equal(t, uint64(10), report.Block.Height)
+	assert.Equal(t, big.NewInt(5), report.Block.Difficulty)
+
+	// the payloads of the agents before the protocol version are accepted
+	msg, err = Parse([]byte(`{"emit":["stats",{"id":"node1","netVersion":1,"shard":2,"stats":{"active":true}}]}`))
+	assert.NoError(t, err)
+	var stats StatsReport
+	assert.NoError(t, msg.Decode(&stats))
+	assert.True(t, stats.Stats.Active)
+}
+
+func TestInvalidMessages(t *testing.T) {
+	_, err := Encode(EventStats, &StatsReport{Header: NewHeader("", 1, 1), Stats: &NodeStats{}})
+	assert.EqualError(t, err, "protocol: invalid stats payload: missing id")
+
+	for data, expected := range map[string]string{
+		`{"emit":[]}`:          "protocol: invalid message: emit should be [event, payload], got 0 items",
+		`{"emit":[1,{}]}`:      "protocol: invalid message: invalid event 1",
+		`{"emit":["a","b",1]}`: "protocol: invalid message: emit should be [event, payload], got 3 items",
+	} {
+		_, err = Parse([]byte(data))
+		assert.EqualError(t, err, expected, data)
+	}
+
+	for data, expected := range map[string]string{
+		`{"emit":["block"]}`: "protocol: block without payload",
+		`{"emit":["block",{"id":"node1","block":{"height":1}}]}`:   "protocol: invalid block payload: missing block headHash",
+		`{"emit":["hello",{"id":"node1","protocolVersion":2}]}`:    "protocol: invalid hello payload: unsupported protocol version 2, max 1",
+		`{"emit":["history",{"id":"node1","chunk":2,"chunks":1}]}`: "protocol: invalid history payload: invalid history chunk 2/1",
+		`{"emit":["unknown",{}]}`:                                  "protocol: unknown event unknown",
+		`{"emit":["set-interval",{"full":0}]}`:                     "protocol: invalid set-interval payload: set-interval should have full or block",
+		`{"emit":["set-interval",{"full":5000,"block":10}]}`:       "protocol: invalid set-interval payload: set-interval periods should be at least 1000ms",
+		`{"emit":["error",{"id":"node1","command":"reboot"}]}`:     "protocol: invalid error payload: missing error",
+		`{"emit":["shard-change",{"id":"node1","from":1,"to":1}]}`: "protocol: invalid shard-change payload: shard-change to the same shard 1",
+	} {
+		msg, err := Parse([]byte(data))
+		assert.NoError(t, err, data)
+		_, err = msg.DecodePayload()
+		assert.EqualError(t, err, expected, data)
+	}
+
+	_, err = Parse([]byte(`{"emit":"stats"}`))
+	assert.Contains(t, err.Error(), "protocol: invalid message: json: cannot unmarshal")
+	msg, err := Parse([]byte(`{"emit":["stats",{"id":"node1","stats":{"active":"true"}}]}`))
+	assert.NoError(t, err)
+	_, err = msg.DecodePayload()
+	assert.Contains(t, err.Error(), "protocol: decode stats payload: json: cannot unmarshal")
+
+	msg, err = Parse([]byte(`{"emit":["history",{"min":5,"max":1}]}`))
+	assert.NoError(t, err)
+	assert.EqualError(t, msg.Decode(&HistoryRequest{}), "protocol: invalid history payload: history request min 5 > max 1")
+}
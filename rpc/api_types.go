@@ -39,6 +39,7 @@ type NodeStats struct {
 // CurrentBlock is the informations about the best block
 type CurrentBlock struct {
 	HeadHash   string   `json:"headHash"`
+	ParentHash string   `json:"parentHash"`
 	Height     uint64   `json:"height"`
 	Timestamp  *big.Int `json:"timestamp"`
 	Difficulty *big.Int `json:"difficulty"`
@@ -46,6 +47,14 @@ type CurrentBlock struct {
 	TxCount    int      `json:"txcount"`
 }
 
+// FullReport is the node data of a full report, fetched in one batch request
+type FullReport struct {
+	NodeInfo     *NodeInfo
+	NodeStats    *NodeStats
+	Info         map[string]interface{}
+	CurrentBlock *CurrentBlock
+}
+
 // MinerInfo miner simple info
 type MinerInfo struct {
 	Coinbase           common.Address
@@ -0,0 +1,112 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package rpc
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+)
+
+// ErrBlockNotFound is returned when the node has no block at the height
+var ErrBlockNotFound = errors.New("block not found")
+
+// bigNumber decodes a JSON number, a quoted decimal or a quoted 0x hex
+// string into a big.Int without going through float64.
+type bigNumber struct {
+	big.Int
+}
+
+func (n *bigNumber) UnmarshalJSON(raw []byte) error {
+	text := string(bytes.TrimSpace(raw))
+	if text == "null" {
+		return errors.New("invalid number null")
+	}
+	if strings.HasPrefix(text, `"`) {
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			return err
+		}
+		// base 0 accepts the 0x prefix
+		if _, ok := n.SetString(strings.TrimSpace(s), 0); !ok {
+			return fmt.Errorf("invalid number %q", s)
+		}
+		return nil
+	}
+
+	if _, ok := n.SetString(text, 10); ok {
+		return nil
+	}
+	// an integer written with a fraction or an exponent, like 1e+21
+	f, _, err := big.ParseFloat(text, 10, 256, big.ToNearestEven)
+	if err != nil || !f.IsInt() {
+		return fmt.Errorf("invalid integer %s", text)
+	}
+	f.Int(&n.Int)
+	return nil
+}
+
+// rpcBlockHeader is the header of the block returned by seele_getBlockByHeight
+type rpcBlockHeader struct {
+	PreviousBlockHash string     `json:"PreviousBlockHash"`
+	Creator           string     `json:"Creator"`
+	Height            *bigNumber `json:"Height"`
+	CreateTimestamp   *bigNumber `json:"CreateTimestamp"`
+	Difficulty        *bigNumber `json:"Difficulty"`
+}
+
+// rpcBlock is the block returned by seele_getBlockByHeight, the transactions
+// are hashes or full transactions, only counted.
+type rpcBlock struct {
+	Hash         string            `json:"hash"`
+	Header       *rpcBlockHeader   `json:"header"`
+	Transactions []json.RawMessage `json:"transactions"`
+}
+
+// decodeBlock validates the seele_getBlockByHeight result and converts it
+// to CurrentBlock, a null result means the block is not found.
+func decodeBlock(raw json.RawMessage) (*CurrentBlock, error) {
+	raw = bytes.TrimSpace(raw)
+	if len(raw) == 0 || bytes.Equal(raw, null) {
+		return nil, ErrBlockNotFound
+	}
+
+	var block rpcBlock
+	if err := json.Unmarshal(raw, &block); err != nil {
+		return nil, fmt.Errorf("decode block error: %v", err)
+	}
+	if block.Hash == "" {
+		return nil, errors.New("decode block error: missing hash")
+	}
+	header := block.Header
+	if header == nil {
+		return nil, fmt.Errorf("decode block %v error: missing header", block.Hash)
+	}
+	switch {
+	case header.Height == nil:
+		return nil, fmt.Errorf("decode block %v error: missing header Height", block.Hash)
+	case header.CreateTimestamp == nil:
+		return nil, fmt.Errorf("decode block %v error: missing header CreateTimestamp", block.Hash)
+	case header.Difficulty == nil:
+		return nil, fmt.Errorf("decode block %v error: missing header Difficulty", block.Hash)
+	}
+	if !header.Height.IsUint64() {
+		return nil, fmt.Errorf("decode block %v error: invalid header Height %v", block.Hash, &header.Height.Int)
+	}
+
+	return &CurrentBlock{
+		HeadHash:   block.Hash,
+		ParentHash: header.PreviousBlockHash,
+		Height:     header.Height.Uint64(),
+		Timestamp:  new(big.Int).Set(&header.CreateTimestamp.Int),
+		Difficulty: new(big.Int).Set(&header.Difficulty.Int),
+		Creator:    header.Creator,
+		TxCount:    len(block.Transactions),
+	}, nil
+}
@@ -0,0 +1,57 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package rpc
+
+import (
+	"encoding/json"
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDecodeBlock(t *testing.T) {
+	block, err := decodeBlock(json.RawMessage(`{
+		"hash": "0xbb",
+		"header": {
+			"PreviousBlockHash": "0xaa",
+			"Creator": "0x01",
+			"Height": 7,
+			"CreateTimestamp": "1537258426",
+			"Difficulty": 123456789012345678901234567890
+		},
+		"transactions": ["0x01", "0x02"]
+	}`))
+	assert.NoError(t, err)
+	assert.Equal(t, "0xbb", block.HeadHash)
+	assert.Equal(t, "0xaa", block.ParentHash)
+	assert.Equal(t, uint64(7), block.Height)
+	assert.Equal(t, big.NewInt(1537258426), block.Timestamp)
+	difficulty, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	assert.Equal(t, difficulty, block.Difficulty)
+	assert.Equal(t, 2, block.TxCount)
+
+	block, err = decodeBlock(json.RawMessage(`{"hash": "0xbb", "header": {"Height": "0x10", "CreateTimestamp": 1e+3, "Difficulty": 5}}`))
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(16), block.Height)
+	assert.Equal(t, big.NewInt(1000), block.Timestamp)
+	assert.Equal(t, 0, block.TxCount)
+
+	for raw, message := range map[string]string{
+		`null`:             "block not found",
+		`[]`:               "decode block error",
+		`{"hash": "0xbb"}`: "missing header",
+		`{"hash": "0xbb", "header": {"Height": 1}}`:                                                           "missing header CreateTimestamp",
+		`{"hash": "0xbb", "header": {"Height": -1, "CreateTimestamp": 1, "Difficulty": 1}}`:                   "invalid header Height",
+		`{"hash": "0xbb", "header": {"Height": 1.5, "CreateTimestamp": 1, "Difficulty": 1}}`:                  "invalid integer 1.5",
+		`{"hash": "0xbb", "header": {"Height": 1, "CreateTimestamp": 1, "Difficulty": 1}, "transactions": 3}`: "decode block error",
+	} {
+		_, err = decodeBlock(json.RawMessage(raw))
+		if assert.Error(t, err, raw) {
+			assert.Contains(t, err.Error(), message, raw)
+		}
+	}
+}
@@ -5,6 +5,7 @@
 package rpc
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"io"
@@ -13,6 +14,7 @@ import (
 	"net/rpc"
 	"reflect"
 	"sync"
+	"time"
 )
 
 const seqNotify = math.MaxUint64
@@ -31,6 +33,22 @@ type clientCodec struct {
 	// and then look it up by request ID when filling out the rpc Response.
 	mutex   sync.Mutex        // protects pending
 	pending map[uint64]string // map request id to method name
+
+	// Between beginBatch and endBatch the requests are buffered and written
+	// as one JSON-RPC 2.0 batch once batchLeft requests are buffered.
+	batchMutex sync.Mutex // held from beginBatch to endBatch, one batch at a time
+	wmutex     sync.Mutex // protects batch, batchLeft and enc
+	batch      []*clientRequest
+	batchLeft  int
+
+	// responses of a batch response not read yet
+	queue []json.RawMessage
+}
+
+// batchCodec is a rpc.ClientCodec able to send requests as one batch
+type batchCodec interface {
+	beginBatch(n int)
+	endBatch() error
 }
 
 // NewClientCodec returns a new rpc.ClientCodec using JSON-RPC 2.0 on conn.
@@ -50,10 +68,10 @@ type clientRequest struct {
 	ID      *uint64     `json:"id,omitempty"`
 }
 
-func (c *clientCodec) WriteRequest(r *rpc.Request, param interface{}) error {
-	// If return error: it will be returned as is for this call.
-	// Allow param to be only Array, Slice, Map or Struct.
-	// When param is nil or uninitialized Map or Slice - omit "params".
+// normalizeParam checks the param type, allows param to be only Array, Slice,
+// Map or Struct. When param is nil or uninitialized Map or Slice returns nil
+// to omit "params".
+func normalizeParam(param interface{}) (interface{}, error) {
 	if param != nil {
 		switch k := reflect.TypeOf(param).Kind(); k {
 		case reflect.Map:
@@ -81,24 +99,78 @@ func (c *clientCodec) WriteRequest(r *rpc.Request, param interface{}) error {
 				}
 			case reflect.Array, reflect.Struct, reflect.String, reflect.Ptr, reflect.Interface:
 			default:
-				return NewError(errInternal.Code, "unsupported param type: Ptr to "+k.String())
+				return nil, NewError(errInternal.Code, "unsupported param type: Ptr to "+k.String())
 			}
 		default:
-			return NewError(errInternal.Code, "unsupported param type: "+k.String())
+			return nil, NewError(errInternal.Code, "unsupported param type: "+k.String())
 		}
 	}
+	return param, nil
+}
+
+func (c *clientCodec) WriteRequest(r *rpc.Request, param interface{}) error {
+	// If return error: it will be returned as is for this call.
+	param, err := normalizeParam(param)
+	if err != nil {
+		return err
+	}
 
-	var req clientRequest
+	req := &clientRequest{
+		Version: jsonrpcVersion,
+		Method:  r.ServiceMethod,
+		Params:  param,
+	}
 	if r.Seq != seqNotify {
 		c.mutex.Lock()
 		c.pending[r.Seq] = r.ServiceMethod
 		c.mutex.Unlock()
-		req.ID = &r.Seq
+		// r is reused by net/rpc, a buffered request needs its own id
+		seq := r.Seq
+		req.ID = &seq
+	}
+
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+	if c.batchLeft > 0 {
+		c.batch = append(c.batch, req)
+		c.batchLeft--
+		if c.batchLeft > 0 {
+			return nil
+		}
+		return c.flushBatch()
 	}
-	req.Version = jsonrpcVersion
-	req.Method = r.ServiceMethod
-	req.Params = param
-	if err := c.enc.Encode(&req); err != nil {
+	if err := c.enc.Encode(req); err != nil {
+		return NewError(errInternal.Code, err.Error())
+	}
+	return nil
+}
+
+// beginBatch buffers the next n requests into one batch
+func (c *clientCodec) beginBatch(n int) {
+	c.batchMutex.Lock()
+	c.wmutex.Lock()
+	c.batchLeft = n
+	c.wmutex.Unlock()
+}
+
+// endBatch writes the requests buffered so far, some of the n requests may
+// have failed before reaching the codec.
+func (c *clientCodec) endBatch() error {
+	defer c.batchMutex.Unlock()
+	c.wmutex.Lock()
+	defer c.wmutex.Unlock()
+	return c.flushBatch()
+}
+
+// flushBatch writes the buffered requests as one array, wmutex must be held
+func (c *clientCodec) flushBatch() error {
+	batch := c.batch
+	c.batch = nil
+	c.batchLeft = 0
+	if len(batch) == 0 {
+		return nil
+	}
+	if err := c.enc.Encode(batch); err != nil {
 		return NewError(errInternal.Code, err.Error())
 	}
 	return nil
@@ -120,8 +192,8 @@ func (r *clientResponse) reset() {
 
 func (r *clientResponse) UnmarshalJSON(raw []byte) error {
 	r.reset()
-	type resp *clientResponse
-	if err := json.Unmarshal(raw, resp(r)); err != nil {
+	type resp clientResponse
+	if err := json.Unmarshal(raw, (*resp)(r)); err != nil {
 		return errors.New("bad response: " + string(raw))
 	}
 
@@ -169,10 +241,29 @@ func (c *clientCodec) ReadResponseHeader(r *rpc.Response) error {
 	// - it will be returned as is for all pending calls
 	// - client will be shutdown
 	// So, return io.EOF as is, return *Error for all other errors.
-	if err := c.dec.Decode(&c.resp); err != nil {
-		if err == io.EOF {
-			return err
+	if len(c.queue) == 0 {
+		var raw json.RawMessage
+		if err := c.dec.Decode(&raw); err != nil {
+			if err == io.EOF {
+				return err
+			}
+			return NewError(errInternal.Code, err.Error())
 		}
+		if len(raw) > 0 && raw[0] == '[' {
+			// a batch response, its items are read one by one
+			if err := json.Unmarshal(raw, &c.queue); err != nil {
+				return NewError(errInternal.Code, err.Error())
+			}
+			if len(c.queue) == 0 {
+				return NewError(errInternal.Code, "bad response: empty batch")
+			}
+		} else {
+			c.queue = []json.RawMessage{raw}
+		}
+	}
+	raw := c.queue[0]
+	c.queue = c.queue[1:]
+	if err := json.Unmarshal(raw, &c.resp); err != nil {
 		return NewError(errInternal.Code, err.Error())
 	}
 	if c.resp.ID == nil {
@@ -222,6 +313,7 @@ func (c *clientCodec) Close() error {
 type Client struct {
 	*rpc.Client
 	codec rpc.ClientCodec
+	conn  net.Conn // nil if the client is not on a net.Conn, then no socket deadlines
 }
 
 // Notify try to invoke the named function. It return error only in case
@@ -234,16 +326,125 @@ func (c Client) Notify(serviceMethod string, args interface{}) error {
 	return c.codec.WriteRequest(req, args)
 }
 
+// CallContext invokes the named function like Call, it returns ctx.Err() once
+// the ctx is done. The ctx deadline is also set on the socket, so a hung server
+// shuts the client down. The response of a canceled call is dropped, the
+// caller should close the client if it must not be reused.
+func (c *Client) CallContext(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok && c.conn != nil {
+		if err := c.conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+		defer c.conn.SetDeadline(time.Time{})
+	}
+
+	call := c.Go(serviceMethod, args, reply, make(chan *rpc.Call, 1))
+	select {
+	case <-call.Done:
+		if call.Error != nil {
+			if err := contextError(ctx); err != nil {
+				return err
+			}
+		}
+		return call.Error
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// BatchElem is one call of a batch request
+type BatchElem struct {
+	Method string
+	Args   interface{}
+	Result interface{} // the result is decoded into it like the reply of Call
+	Error  error       // the error of this call, set by Batch
+}
+
+// Batch sends the elems as one JSON-RPC 2.0 batch request and waits for all
+// the responses. The error of each call is set in its BatchElem, Batch only
+// returns the error which failed the whole batch.
+func (c *Client) Batch(elems []BatchElem) error {
+	return c.BatchContext(context.Background(), elems)
+}
+
+// BatchContext sends the batch like Batch, it returns ctx.Err() once the ctx
+// is done, see CallContext.
+func (c *Client) BatchContext(ctx context.Context, elems []BatchElem) error {
+	if len(elems) == 0 {
+		return nil
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok && c.conn != nil {
+		if err := c.conn.SetDeadline(deadline); err != nil {
+			return err
+		}
+		defer c.conn.SetDeadline(time.Time{})
+	}
+
+	done := make(chan *rpc.Call, len(elems))
+	calls := make([]*rpc.Call, len(elems))
+	codec, batching := c.codec.(batchCodec)
+	if batching {
+		codec.beginBatch(len(elems))
+	}
+	for i, elem := range elems {
+		calls[i] = c.Go(elem.Method, elem.Args, elem.Result, done)
+	}
+	if batching {
+		if err := codec.endBatch(); err != nil {
+			return err
+		}
+	}
+
+	for range calls {
+		select {
+		case <-done:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if err := contextError(ctx); err != nil {
+		return err
+	}
+	var err error
+	for i, call := range calls {
+		elems[i].Error = call.Error
+		if err == nil && isConnError(call.Error) {
+			err = call.Error
+		}
+	}
+	return err
+}
+
+// contextError returns the error of the ctx, a passed deadline counts as
+// exceeded, the socket deadline may fire before the ctx is done.
+func contextError(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if deadline, ok := ctx.Deadline(); ok && !time.Now().Before(deadline) {
+		return context.DeadlineExceeded
+	}
+	return nil
+}
+
 // NewClient returns a new Client to handle requests to the
 // set of services at the other end of the connection.
 func NewClient(conn io.ReadWriteCloser) *Client {
-	return NewClientWithCodec(NewClientCodec(conn))
+	client := NewClientWithCodec(NewClientCodec(conn))
+	client.conn, _ = conn.(net.Conn)
+	return client
 }
 
 // NewClientWithCodec returns a new Client using the given rpc.ClientCodec.
 func NewClientWithCodec(codec rpc.ClientCodec) *Client {
 	client := rpc.NewClientWithCodec(codec)
-	return &Client{client, codec}
+	return &Client{Client: client, codec: codec}
 }
 
 // Dial connects to a JSON-RPC 2.0 server at the specified network address.
@@ -254,3 +455,14 @@ func Dial(network, address string) (*Client, error) {
 	}
 	return NewClient(conn), err
 }
+
+// DialContext connects to a JSON-RPC 2.0 server at the specified network address
+// using the ctx for the dial.
+func DialContext(ctx context.Context, network, address string) (*Client, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, network, address)
+	if err != nil {
+		return nil, err
+	}
+	return NewClient(conn), err
+}
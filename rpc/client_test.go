@@ -0,0 +1,166 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientBatch(t *testing.T) {
+	addr, conns, stop := newTestServer(t)
+	defer stop()
+
+	client, err := Dial("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	var a, b, bad string
+	elems := []BatchElem{
+		{Method: "Echo.Echo", Args: []string{"a"}, Result: &a},
+		{Method: "Echo.Sleep", Args: []string{"not a duration"}, Result: &bad},
+		{Method: "Echo.Echo", Args: []string{"b"}, Result: &b},
+	}
+	assert.NoError(t, client.Batch(elems))
+	assert.NoError(t, elems[0].Error)
+	assert.Error(t, elems[1].Error)
+	assert.NoError(t, elems[2].Error)
+	assert.Equal(t, "a", a)
+	assert.Equal(t, "b", b)
+
+	// the client still works after the batch
+	var reply string
+	assert.NoError(t, client.Call("Echo.Echo", []string{"c"}, &reply))
+	assert.Equal(t, "c", reply)
+	assert.Equal(t, int32(1), atomic.LoadInt32(conns))
+}
+
+func TestClientBatchOneRoundTrip(t *testing.T) {
+	server, client := net.Pipe()
+	defer server.Close()
+	c := NewClient(client)
+	defer c.Close()
+
+	go func() {
+		// the batch arrives as one array, answered in reverse order
+		var requests []struct {
+			ID uint64 `json:"id"`
+		}
+		if err := json.NewDecoder(server).Decode(&requests); err != nil {
+			return
+		}
+		var responses []map[string]interface{}
+		for i := len(requests) - 1; i >= 0; i-- {
+			responses = append(responses, map[string]interface{}{"jsonrpc": "2.0", "id": requests[i].ID, "result": requests[i].ID})
+		}
+		json.NewEncoder(server).Encode(responses)
+	}()
+
+	results := make([]uint64, 3)
+	elems := make([]BatchElem, len(results))
+	for i := range elems {
+		elems[i] = BatchElem{Method: "seele_getInfo", Result: &results[i]}
+	}
+	assert.NoError(t, c.Batch(elems))
+	for i := range elems {
+		assert.NoError(t, elems[i].Error)
+	}
+	assert.True(t, results[0] < results[1] && results[1] < results[2])
+}
+
+func TestHTTPBatch(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var requests []struct {
+			Method string `json:"method"`
+			ID     uint64 `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&requests); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var responses []map[string]interface{}
+		for _, req := range requests {
+			responses = append(responses, fullReportResponse(req.Method, req.ID))
+		}
+		json.NewEncoder(w).Encode(responses)
+	}))
+	defer server.Close()
+
+	client := NewSeeleRPC(server.URL)
+	defer client.Close()
+	assertFullReport(t, client)
+
+	var result interface{}
+	elems := []BatchElem{{Method: "seele_unknown", Result: &result}}
+	assert.NoError(t, client.batchContext(context.Background(), elems))
+	assert.Equal(t, errMethod.Code, elems[0].Error.(*Error).Code)
+}
+
+// fullReportResponse is the response of the test node to the calls of FullReport
+func fullReportResponse(method string, id uint64) map[string]interface{} {
+	switch method {
+	case "monitor_nodeInfo":
+		return map[string]interface{}{"jsonrpc": "2.0", "id": id, "result": map[string]interface{}{"netVersion": "1", "shard": 2}}
+	case "monitor_nodeStats":
+		return map[string]interface{}{"jsonrpc": "2.0", "id": id, "result": map[string]interface{}{"active": true, "peers": 3}}
+	case "miner_getHashrate":
+		return map[string]interface{}{"jsonrpc": "2.0", "id": id, "result": 42}
+	case "seele_getInfo":
+		return map[string]interface{}{"jsonrpc": "2.0", "id": id, "result": map[string]interface{}{"Coinbase": "0x01"}}
+	case "seele_getBlockByHeight":
+		return map[string]interface{}{"jsonrpc": "2.0", "id": id, "result": map[string]interface{}{
+			"hash":         "0xbb",
+			"header":       map[string]interface{}{"CreateTimestamp": 1, "Difficulty": 2, "Height": 7, "Creator": "0x01", "PreviousBlockHash": "0xaa"},
+			"transactions": []interface{}{},
+		}}
+	}
+	return map[string]interface{}{"jsonrpc": "2.0", "id": id, "error": map[string]interface{}{"code": -32601, "message": "Method not found"}}
+}
+
+func assertFullReport(t *testing.T, client *MonitorRPC) {
+	full, err := client.FullReport()
+	if !assert.NoError(t, err) {
+		return
+	}
+	assert.Equal(t, uint(2), full.NodeInfo.Shard)
+	assert.True(t, full.NodeStats.Active)
+	assert.Equal(t, uint64(42), full.NodeStats.Hashrate)
+	assert.Equal(t, "0x01", full.Info["Coinbase"])
+	assert.Equal(t, uint64(7), full.CurrentBlock.Height)
+	assert.Equal(t, "0xaa", full.CurrentBlock.ParentHash)
+}
+
+// a node rejecting the batch requests is called once per method
+func TestFullReportWithoutBatch(t *testing.T) {
+	var batches int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var request struct {
+			Method string `json:"method"`
+			ID     uint64 `json:"id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			atomic.AddInt32(&batches, 1)
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		json.NewEncoder(w).Encode(fullReportResponse(request.Method, request.ID))
+	}))
+	defer server.Close()
+
+	client := NewSeeleRPC(server.URL)
+	defer client.Close()
+	assertFullReport(t, client)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&batches))
+}
@@ -0,0 +1,85 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package rpc
+
+import (
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMonitorRPCFailover(t *testing.T) {
+	// the primary is down
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	primary := listener.Addr().String()
+	listener.Close()
+	standby, _, stopStandby := newTestServer(t)
+	defer stopStandby()
+
+	var switches [][2]string
+	client := NewSeeleRPC(primary,
+		WithFallbacks(standby),
+		WithFailbackInterval(20*time.Millisecond),
+		WithFailover(func(from, to string) {
+			switches = append(switches, [2]string{from, to})
+		}))
+	defer client.Close()
+	assert.Equal(t, primary, client.CurrentEndpoint())
+
+	var reply string
+	assert.NoError(t, client.call("Echo.Echo", []string{"a"}, &reply))
+	assert.Equal(t, "a", reply)
+	assert.Equal(t, standby, client.CurrentEndpoint())
+
+	// a node error does not fail over
+	assert.Error(t, client.call("Echo.Sleep", []string{"not a duration"}, &reply))
+	assert.Equal(t, standby, client.CurrentEndpoint())
+
+	// the primary recovers, the client fails back after the interval
+	_, _, stopPrimary := newTestServerAt(t, primary)
+	defer stopPrimary()
+	assert.NoError(t, client.call("Echo.Echo", []string{"b"}, &reply))
+	assert.Equal(t, standby, client.CurrentEndpoint())
+	time.Sleep(30 * time.Millisecond)
+	assert.NoError(t, client.call("Echo.Echo", []string{"c"}, &reply))
+	assert.Equal(t, "c", reply)
+	assert.Equal(t, primary, client.CurrentEndpoint())
+	assert.Equal(t, [][2]string{{primary, standby}, {standby, primary}}, switches)
+}
+
+func TestMonitorRPCFailoverOnTimeout(t *testing.T) {
+	// the primary accepts the connections but never answers
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	standby, _, stopStandby := newTestServer(t)
+	defer stopStandby()
+
+	client := NewSeeleRPC(listener.Addr().String(), WithFallbacks(standby), WithTimeout(50*time.Millisecond))
+	defer client.Close()
+	var reply string
+	start := time.Now()
+	assert.NoError(t, client.call("Echo.Echo", []string{"a"}, &reply))
+	assert.Equal(t, "a", reply)
+	assert.Equal(t, standby, client.CurrentEndpoint())
+	assert.True(t, time.Since(start) < time.Second)
+}
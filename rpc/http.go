@@ -0,0 +1,206 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+)
+
+const (
+	schemeTCP   = "tcp"
+	schemeHTTP  = "http"
+	schemeHTTPS = "https"
+
+	maxHTTPResponseSize = 32 << 20 // 32MB
+)
+
+// httpTransport posts JSON-RPC 2.0 bodies to the http endpoint of the node
+type httpTransport struct {
+	url      string
+	client   *http.Client
+	username string
+	password string
+	headers  map[string]string
+	seq      uint64
+}
+
+func newHTTPTransport(url string, rpc *MonitorRPC) (*httpTransport, error) {
+	transport := &http.Transport{
+		Proxy:               http.ProxyFromEnvironment,
+		MaxIdleConnsPerHost: rpc.poolSize,
+		IdleConnTimeout:     rpc.idleTimeout,
+	}
+	if rpc.caFile != "" {
+		caCert, err := ioutil.ReadFile(rpc.caFile)
+		if err != nil {
+			return nil, fmt.Errorf("rpc: read CA file %v error: %v", rpc.caFile, err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(caCert) {
+			return nil, fmt.Errorf("rpc: no certificate found in CA file %v", rpc.caFile)
+		}
+		transport.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+
+	return &httpTransport{
+		url:      url,
+		client:   &http.Client{Transport: transport},
+		username: rpc.username,
+		password: rpc.password,
+		headers:  rpc.headers,
+	}, nil
+}
+
+func (t *httpTransport) call(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	param, err := normalizeParam(args)
+	if err != nil {
+		return err
+	}
+	id := atomic.AddUint64(&t.seq, 1)
+	body, err := json.Marshal(&clientRequest{
+		Version: jsonrpcVersion,
+		Method:  serviceMethod,
+		Params:  param,
+		ID:      &id,
+	})
+	if err != nil {
+		return NewError(errInternal.Code, err.Error())
+	}
+
+	var resp clientResponse
+	if err = t.post(ctx, body, &resp); err != nil {
+		return err
+	}
+	if resp.Error != nil {
+		return resp.Error
+	}
+	if resp.ID == nil || *resp.ID != id {
+		return fmt.Errorf("rpc: %s response id mismatch", serviceMethod)
+	}
+	if reply == nil {
+		return nil
+	}
+	return json.Unmarshal(*resp.Result, reply)
+}
+
+func (t *httpTransport) batch(ctx context.Context, elems []BatchElem) error {
+	requests := make([]*clientRequest, 0, len(elems))
+	indexes := make(map[uint64]int, len(elems))
+	for i := range elems {
+		elems[i].Error = nil
+		param, err := normalizeParam(elems[i].Args)
+		if err != nil {
+			elems[i].Error = err
+			continue
+		}
+		id := atomic.AddUint64(&t.seq, 1)
+		indexes[id] = i
+		requests = append(requests, &clientRequest{
+			Version: jsonrpcVersion,
+			Method:  elems[i].Method,
+			Params:  param,
+			ID:      &id,
+		})
+	}
+	if len(requests) == 0 {
+		return nil
+	}
+	body, err := json.Marshal(requests)
+	if err != nil {
+		return NewError(errInternal.Code, err.Error())
+	}
+
+	var raw json.RawMessage
+	if err = t.post(ctx, body, &raw); err != nil {
+		return err
+	}
+	if len(raw) > 0 && raw[0] != '[' {
+		// the node rejected the whole batch
+		var resp clientResponse
+		if err = json.Unmarshal(raw, &resp); err != nil {
+			return err
+		}
+		if resp.Error != nil {
+			return resp.Error
+		}
+		return errors.New("rpc: batch response is not an array")
+	}
+	var responses []clientResponse
+	if err = json.Unmarshal(raw, &responses); err != nil {
+		return err
+	}
+
+	for _, resp := range responses {
+		if resp.ID == nil {
+			if resp.Error != nil {
+				return resp.Error
+			}
+			continue
+		}
+		i, ok := indexes[*resp.ID]
+		if !ok {
+			continue
+		}
+		delete(indexes, *resp.ID)
+		if resp.Error != nil {
+			elems[i].Error = resp.Error
+		} else if elems[i].Result != nil {
+			elems[i].Error = json.Unmarshal(*resp.Result, elems[i].Result)
+		}
+	}
+	for _, i := range indexes {
+		elems[i].Error = fmt.Errorf("rpc: %s has no response in the batch", elems[i].Method)
+	}
+	return nil
+}
+
+// post sends the body and decodes the response body into result
+func (t *httpTransport) post(ctx context.Context, body []byte, result interface{}) error {
+	req, err := http.NewRequest(http.MethodPost, t.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json")
+	for k, v := range t.headers {
+		req.Header.Set(k, v)
+	}
+	if t.username != "" || t.password != "" {
+		req.SetBasicAuth(t.username, t.password)
+	}
+
+	httpResp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer httpResp.Body.Close()
+
+	respBody, err := ioutil.ReadAll(io.LimitReader(httpResp.Body, maxHTTPResponseSize))
+	if err != nil {
+		return err
+	}
+	if httpResp.StatusCode != http.StatusOK {
+		return fmt.Errorf("rpc: http status %v: %s", httpResp.Status, bytes.TrimSpace(respBody))
+	}
+	return json.Unmarshal(respBody, result)
+}
+
+func (t *httpTransport) close() {
+	if transport, ok := t.client.Transport.(*http.Transport); ok {
+		transport.CloseIdleConnections()
+	}
+}
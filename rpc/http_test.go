@@ -0,0 +1,80 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package rpc
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPTransport(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, pass, ok := r.BasicAuth()
+		if !ok || user != "seele" || pass != "secret" || r.Header.Get("X-Node") != "alpha" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+			ID     uint64        `json:"id"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Method == "seele_getBlockByHeight" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": nil})
+			return
+		}
+		if req.Method != "seele_getInfo" {
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"jsonrpc": "2.0", "id": req.ID, "error": map[string]interface{}{"code": -32601, "message": "Method not found"},
+			})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0", "id": req.ID, "result": map[string]interface{}{"Coinbase": "0x01"},
+		})
+	}))
+	defer server.Close()
+
+	client := NewSeeleRPC(server.URL, WithBasicAuth("seele", "secret"), WithHeaders(map[string]string{"X-Node": "alpha"}))
+	defer client.Close()
+	info, err := client.GetInfo()
+	assert.NoError(t, err)
+	assert.Equal(t, "0x01", info["Coinbase"])
+
+	_, err = client.CurrentBlock(5, false)
+	if assert.Error(t, err) {
+		assert.Equal(t, "rpc: seele_getBlockByHeight 5: block not found", err.Error())
+	}
+
+	var result interface{}
+	err = client.call("seele_unknown", nil, &result)
+	assert.Error(t, err)
+	assert.Equal(t, errMethod.Code, err.(*Error).Code)
+
+	unauthorized := NewSeeleRPC(server.URL)
+	_, err = unauthorized.GetInfo()
+	assert.Error(t, err)
+}
+
+func TestHTTPScheme(t *testing.T) {
+	rpc := NewSeeleRPC("127.0.0.1:8080", WithScheme("https"))
+	assert.Equal(t, "https://127.0.0.1:8080", rpc.CurrentEndpoint())
+	_, ok := rpc.endpoints[0].transport.(*httpTransport)
+	assert.True(t, ok)
+
+	rpc = NewSeeleRPC("127.0.0.1:55027")
+	_, ok = rpc.endpoints[0].transport.(*clientPool)
+	assert.True(t, ok)
+
+	rpc = NewSeeleRPC("https://127.0.0.1:8080", WithCAFile("not-exist.pem"))
+	_, err := rpc.GetInfo()
+	assert.Error(t, err)
+}
@@ -61,8 +61,8 @@ type jsonRequest struct {
 
 func (r *jsonRequest) UnmarshalJSON(raw []byte) error {
 	r.reset()
-	type req *jsonRequest
-	if err := json.Unmarshal(raw, req(r)); err != nil {
+	type req jsonRequest
+	if err := json.Unmarshal(raw, (*req)(r)); err != nil {
 		return errors.New("bad request")
 	}
 	var reqMap = make(map[string]*json.RawMessage)
@@ -0,0 +1,19 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package rpc
+
+import (
+	"github.com/seeleteam/monitor-api/core/metrics"
+)
+
+var (
+	callDuration = metrics.NewHistogramVec("monitor_api_rpc_call_duration_seconds",
+		"Duration of the rpc calls to the go-seele node, a batch is one call.", nil, "endpoint", "method")
+	callErrors = metrics.NewCounterVec("monitor_api_rpc_errors_total",
+		"Failed rpc calls to the go-seele node.", "endpoint", "method")
+	failovers = metrics.NewCounterVec("monitor_api_rpc_failovers_total",
+		"Switches of the rpc endpoint in use.", "from", "to")
+)
@@ -6,18 +6,29 @@
 package rpc
 
 import (
-	"math/big"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
 )
 
 // NodeStats returns the current node info.
 func (rpc *MonitorRPC) NodeStats() (nodeStats *NodeStats, err error) {
-	err = rpc.call("monitor_nodeStats", nil, &nodeStats)
+	return rpc.NodeStatsContext(context.Background())
+}
+
+// NodeStatsContext returns the current node info, canceled with the ctx.
+func (rpc *MonitorRPC) NodeStatsContext(ctx context.Context) (nodeStats *NodeStats, err error) {
+	err = rpc.callContext(ctx, "monitor_nodeStats", nil, &nodeStats)
 	if err != nil {
 		return
 	}
+	if nodeStats == nil {
+		return nil, errors.New("rpc: monitor_nodeStats returns null")
+	}
 
 	var hashrate uint64
-	err = rpc.call("miner_getHashrate", nil, &hashrate)
+	err = rpc.callContext(ctx, "miner_getHashrate", nil, &hashrate)
 	if err != nil {
 		return
 	}
@@ -27,12 +38,23 @@ func (rpc *MonitorRPC) NodeStats() (nodeStats *NodeStats, err error) {
 
 // NodeInfo returns the current node info.
 func (rpc *MonitorRPC) NodeInfo() (nodeInfo *NodeInfo, err error) {
-	err = rpc.call("monitor_nodeInfo", nil, &nodeInfo)
+	return rpc.NodeInfoContext(context.Background())
+}
+
+// NodeInfoContext returns the current node info, canceled with the ctx.
+func (rpc *MonitorRPC) NodeInfoContext(ctx context.Context) (nodeInfo *NodeInfo, err error) {
+	err = rpc.callContext(ctx, "monitor_nodeInfo", nil, &nodeInfo)
 	return nodeInfo, err
 }
 
 // CurrentBlock returns the current block info.
 func (rpc *MonitorRPC) CurrentBlock(h int64, fullTx bool) (currentBlock *CurrentBlock, err error) {
+	return rpc.CurrentBlockContext(context.Background(), h, fullTx)
+}
+
+// CurrentBlockContext returns the block info at the height, -1 means the
+// current block, canceled with the ctx.
+func (rpc *MonitorRPC) CurrentBlockContext(ctx context.Context, h int64, fullTx bool) (currentBlock *CurrentBlock, err error) {
 	request := GetBlockByHeightRequest{
 		Height: h,
 		FullTx: fullTx,
@@ -40,35 +62,113 @@ func (rpc *MonitorRPC) CurrentBlock(h int64, fullTx bool) (currentBlock *Current
 	var req []interface{}
 	req = append(req, request.Height)
 	req = append(req, request.FullTx)
-	rpcOutputBlock := make(map[string]interface{})
-	if err := rpc.call("seele_getBlockByHeight", req, &rpcOutputBlock); err != nil {
+	var rpcOutputBlock json.RawMessage
+	if err := rpc.callContext(ctx, "seele_getBlockByHeight", req, &rpcOutputBlock); err != nil {
 		return nil, err
 	}
 
-	return getBlockByHeight(rpcOutputBlock, fullTx), err
-}
-func getBlockByHeight(rpcOutputBlock map[string]interface{}, fullTx bool) *CurrentBlock {
-	headerMp := rpcOutputBlock["header"].(map[string]interface{})
-	timestamp := int64(headerMp["CreateTimestamp"].(float64))
-	difficulty := int64(headerMp["Difficulty"].(float64))
-	height := uint64(headerMp["Height"].(float64))
-	creator := headerMp["Creator"].(string)
-
-	return &CurrentBlock{
-		HeadHash:   rpcOutputBlock["hash"].(string),
-		Height:     height,
-		Timestamp:  big.NewInt(timestamp),
-		Difficulty: big.NewInt(difficulty),
-		Creator:    creator,
-		TxCount:    len(rpcOutputBlock["transactions"].([]interface{})),
+	currentBlock, err = decodeBlock(rpcOutputBlock)
+	if err != nil {
+		return nil, fmt.Errorf("rpc: seele_getBlockByHeight %v: %w", h, err)
 	}
+	return currentBlock, nil
 }
 
 // GetInfo gets the account address that mining rewards will be send to.
 func (rpc *MonitorRPC) GetInfo() (result map[string]interface{}, err error) {
-	err = rpc.call("seele_getInfo", nil, &result)
+	return rpc.GetInfoContext(context.Background())
+}
+
+// GetInfoContext gets the account address that mining rewards will be send to,
+// canceled with the ctx.
+func (rpc *MonitorRPC) GetInfoContext(ctx context.Context) (result map[string]interface{}, err error) {
+	err = rpc.callContext(ctx, "seele_getInfo", nil, &result)
 	if err != nil {
 		return nil, err
 	}
 	return result, nil
 }
+
+// FullReport returns the node info, the node stats, the seele info and the
+// current block with the transactions in one batch request.
+func (rpc *MonitorRPC) FullReport() (*FullReport, error) {
+	return rpc.FullReportContext(context.Background())
+}
+
+// FullReportContext returns the data of FullReport, canceled with the ctx. The
+// data is fetched with one call per method if the node rejects the batch.
+func (rpc *MonitorRPC) FullReportContext(ctx context.Context) (*FullReport, error) {
+	var (
+		nodeInfo       *NodeInfo
+		nodeStats      *NodeStats
+		hashrate       uint64
+		info           map[string]interface{}
+		rpcOutputBlock json.RawMessage
+	)
+	elems := []BatchElem{
+		{Method: "monitor_nodeInfo", Result: &nodeInfo},
+		{Method: "monitor_nodeStats", Result: &nodeStats},
+		{Method: "miner_getHashrate", Result: &hashrate},
+		{Method: "seele_getInfo", Result: &info},
+		{Method: "seele_getBlockByHeight", Args: []interface{}{int64(-1), true}, Result: &rpcOutputBlock},
+	}
+	if err := rpc.batchContext(ctx, elems); err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return rpc.fullReportByCalls(ctx)
+	}
+	for _, elem := range elems {
+		if elem.Error != nil {
+			return nil, elem.Error
+		}
+	}
+	if nodeInfo == nil {
+		return nil, errors.New("rpc: monitor_nodeInfo returns null")
+	}
+	if nodeStats == nil {
+		return nil, errors.New("rpc: monitor_nodeStats returns null")
+	}
+	nodeStats.Hashrate = hashrate
+	currentBlock, err := decodeBlock(rpcOutputBlock)
+	if err != nil {
+		return nil, fmt.Errorf("rpc: seele_getBlockByHeight -1: %w", err)
+	}
+
+	return &FullReport{
+		NodeInfo:     nodeInfo,
+		NodeStats:    nodeStats,
+		Info:         info,
+		CurrentBlock: currentBlock,
+	}, nil
+}
+
+// fullReportByCalls returns the data of FullReport with one call per method
+func (rpc *MonitorRPC) fullReportByCalls(ctx context.Context) (*FullReport, error) {
+	nodeInfo, err := rpc.NodeInfoContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if nodeInfo == nil {
+		return nil, errors.New("rpc: monitor_nodeInfo returns null")
+	}
+	nodeStats, err := rpc.NodeStatsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	info, err := rpc.GetInfoContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	currentBlock, err := rpc.CurrentBlockContext(ctx, -1, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FullReport{
+		NodeInfo:     nodeInfo,
+		NodeStats:    nodeStats,
+		Info:         info,
+		CurrentBlock: currentBlock,
+	}, nil
+}
@@ -0,0 +1,202 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package rpc
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"net/rpc"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	defaultPoolSize    = 2
+	defaultIdleTimeout = 60 * time.Second
+	defaultTimeout     = 10 * time.Second
+)
+
+var errPoolClosed = errors.New("rpc: client pool is closed")
+
+// healthCodec marks the connection dead once reading a response fails, so a
+// client broken while idle in the pool is not handed out again.
+type healthCodec struct {
+	rpc.ClientCodec
+	dead int32
+}
+
+func (c *healthCodec) ReadResponseHeader(r *rpc.Response) error {
+	err := c.ClientCodec.ReadResponseHeader(r)
+	if err != nil {
+		atomic.StoreInt32(&c.dead, 1)
+	}
+	return err
+}
+
+func (c *healthCodec) beginBatch(n int) {
+	if codec, ok := c.ClientCodec.(batchCodec); ok {
+		codec.beginBatch(n)
+	}
+}
+
+func (c *healthCodec) endBatch() error {
+	if codec, ok := c.ClientCodec.(batchCodec); ok {
+		return codec.endBatch()
+	}
+	return nil
+}
+
+func (c *healthCodec) isDead() bool {
+	return atomic.LoadInt32(&c.dead) == 1
+}
+
+// pooledClient is a Client kept in the pool
+type pooledClient struct {
+	*Client
+	health   *healthCodec
+	lastUsed time.Time
+}
+
+// clientPool keeps up to size long-lived Clients to the same address.
+type clientPool struct {
+	mu sync.Mutex
+
+	network     string
+	address     string
+	size        int
+	idleTimeout time.Duration
+
+	idle   []*pooledClient
+	closed bool
+}
+
+func newClientPool(network, address string, size int, idleTimeout time.Duration) *clientPool {
+	if size <= 0 {
+		size = defaultPoolSize
+	}
+	return &clientPool{
+		network:     network,
+		address:     address,
+		size:        size,
+		idleTimeout: idleTimeout,
+	}
+}
+
+// get returns a healthy idle client, or dials a new one
+func (p *clientPool) get(ctx context.Context) (*pooledClient, error) {
+	p.mu.Lock()
+	if p.closed {
+		p.mu.Unlock()
+		return nil, errPoolClosed
+	}
+	for len(p.idle) > 0 {
+		c := p.idle[len(p.idle)-1]
+		p.idle = p.idle[:len(p.idle)-1]
+		if c.health.isDead() || (p.idleTimeout > 0 && time.Since(c.lastUsed) > p.idleTimeout) {
+			c.Close()
+			continue
+		}
+		p.mu.Unlock()
+		return c, nil
+	}
+	p.mu.Unlock()
+
+	return p.dial(ctx)
+}
+
+func (p *clientPool) dial(ctx context.Context) (*pooledClient, error) {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, p.network, p.address)
+	if err != nil {
+		return nil, err
+	}
+	health := &healthCodec{ClientCodec: NewClientCodec(conn)}
+	client := NewClientWithCodec(health)
+	client.conn = conn
+	return &pooledClient{
+		Client: client,
+		health: health,
+	}, nil
+}
+
+// put gives the client back to the pool, broken clients or the clients over
+// the pool size are closed.
+func (p *clientPool) put(c *pooledClient, broken bool) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if broken || p.closed || c.health.isDead() || len(p.idle) >= p.size {
+		c.Close()
+		return
+	}
+	c.lastUsed = time.Now()
+	p.idle = append(p.idle, c)
+}
+
+func (p *clientPool) call(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	return p.do(ctx, func(c *pooledClient) error {
+		return c.CallContext(ctx, serviceMethod, args, reply)
+	})
+}
+
+func (p *clientPool) batch(ctx context.Context, elems []BatchElem) error {
+	return p.do(ctx, func(c *pooledClient) error {
+		return c.BatchContext(ctx, elems)
+	})
+}
+
+// do runs the request on a pooled client, a pooled connection may be closed
+// by the node so it redials once. A canceled or timed out request closes the client.
+func (p *clientPool) do(ctx context.Context, request func(c *pooledClient) error) error {
+	for attempt := 0; ; attempt++ {
+		c, err := p.get(ctx)
+		if err != nil {
+			return err
+		}
+
+		err = request(c)
+		if contextError(ctx) != nil {
+			p.put(c, true)
+			return err
+		}
+		if isConnError(err) {
+			p.put(c, true)
+			if attempt == 0 {
+				continue
+			}
+			return err
+		}
+		p.put(c, false)
+		return err
+	}
+}
+
+// close closes all the idle clients, the clients in use are closed on put
+func (p *clientPool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for _, c := range p.idle {
+		c.Close()
+	}
+	p.idle = nil
+}
+
+// isConnError reports whether the error means the connection is unusable
+func isConnError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if err == rpc.ErrShutdown || err == io.EOF || err == io.ErrUnexpectedEOF {
+		return true
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
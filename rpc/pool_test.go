@@ -0,0 +1,131 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package rpc
+
+import (
+	"context"
+	"net"
+	"net/rpc"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Echo is the service served by the test server
+type Echo struct{}
+
+// Echo returns the first param
+func (e *Echo) Echo(param string, reply *string) error {
+	*reply = param
+	return nil
+}
+
+// Sleep hangs for the duration in param
+func (e *Echo) Sleep(param string, reply *string) error {
+	d, err := time.ParseDuration(param)
+	if err != nil {
+		return err
+	}
+	time.Sleep(d)
+	*reply = param
+	return nil
+}
+
+// newTestServer serves Echo on a local tcp address, counting the connections
+func newTestServer(t *testing.T) (string, *int32, func()) {
+	return newTestServerAt(t, "127.0.0.1:0")
+}
+
+// newTestServerAt serves Echo on the address
+func newTestServerAt(t *testing.T, addr string) (string, *int32, func()) {
+	var conns int32
+	srv := rpc.NewServer()
+	if err := srv.Register(&Echo{}); err != nil {
+		t.Fatal(err)
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			atomic.AddInt32(&conns, 1)
+			go srv.ServeCodec(NewJSONCodec(conn, srv))
+		}
+	}()
+	return listener.Addr().String(), &conns, func() { listener.Close() }
+}
+
+func TestMonitorRPCReusesConnection(t *testing.T) {
+	addr, conns, stop := newTestServer(t)
+	defer stop()
+
+	client := NewSeeleRPC(addr)
+	defer client.Close()
+	for _, word := range []string{"a", "b", "c"} {
+		var reply string
+		assert.NoError(t, client.call("Echo.Echo", []string{word}, &reply))
+		assert.Equal(t, word, reply)
+	}
+	assert.Equal(t, int32(1), atomic.LoadInt32(conns))
+}
+
+func TestMonitorRPCRedialsBrokenConnection(t *testing.T) {
+	addr, conns, stop := newTestServer(t)
+	defer stop()
+
+	client := NewSeeleRPC(addr)
+	defer client.Close()
+	var reply string
+	assert.NoError(t, client.call("Echo.Echo", []string{"a"}, &reply))
+
+	// the node closes the idle connection
+	client.endpoints[0].transport.(*clientPool).idle[0].Close()
+	assert.NoError(t, client.call("Echo.Echo", []string{"b"}, &reply))
+	assert.Equal(t, "b", reply)
+	assert.Equal(t, int32(2), atomic.LoadInt32(conns))
+}
+
+func TestClientPoolIdleTimeout(t *testing.T) {
+	addr, conns, stop := newTestServer(t)
+	defer stop()
+
+	client := NewSeeleRPC(addr, WithIdleTimeout(time.Millisecond))
+	defer client.Close()
+	var reply string
+	assert.NoError(t, client.call("Echo.Echo", []string{"a"}, &reply))
+	time.Sleep(5 * time.Millisecond)
+	assert.NoError(t, client.call("Echo.Echo", []string{"b"}, &reply))
+	assert.Equal(t, int32(2), atomic.LoadInt32(conns))
+}
+
+func TestMonitorRPCTimeout(t *testing.T) {
+	addr, conns, stop := newTestServer(t)
+	defer stop()
+
+	client := NewSeeleRPC(addr, WithTimeout(50*time.Millisecond))
+	defer client.Close()
+	var reply string
+	start := time.Now()
+	err := client.call("Echo.Sleep", []string{"1s"}, &reply)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.True(t, time.Since(start) < time.Second)
+
+	// the timed out connection is not reused
+	assert.NoError(t, client.call("Echo.Echo", []string{"a"}, &reply))
+	assert.Equal(t, int32(2), atomic.LoadInt32(conns))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = client.NodeInfoContext(ctx)
+	assert.Equal(t, context.Canceled, err)
+}
@@ -6,6 +6,11 @@
 package rpc
 
 import (
+	"context"
+	"strings"
+	"sync"
+	"time"
+
 	"github.com/seeleteam/monitor-api/core/logs"
 )
 
@@ -13,48 +18,311 @@ type logger interface {
 	Println(v ...interface{})
 }
 
-// MonitorRPC json_rpc client
+// transport sends the JSON-RPC 2.0 calls to the node
+type transport interface {
+	call(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error
+	batch(ctx context.Context, elems []BatchElem) error
+	close()
+}
+
+const defaultFailbackInterval = 30 * time.Second
+
+// endpoint is a url of the node with its transport
+type endpoint struct {
+	url       string
+	transport transport
+}
+
+// MonitorRPC json_rpc client, the calls go to the current endpoint and fail
+// over to the next endpoints in order on connection errors or timeouts.
 type MonitorRPC struct {
-	url    string
-	scheme string
-	Debug  bool
+	url         string
+	scheme      string
+	poolSize    int           // max idle connections kept
+	idleTimeout time.Duration // idle connections older than this are redialed
+	timeout     time.Duration // timeout of a call on one endpoint, 0 means no timeout
+	Debug       bool
+
+	// failover config
+	fallbacks        []string              // urls of the standby endpoints of the same node, in order
+	failbackInterval time.Duration         // interval to try the primary endpoint again, 0 means never
+	onFailover       func(from, to string) // called when the current endpoint changes
+
+	endpoints []*endpoint // the primary endpoint first
+	mu        sync.Mutex  // protects current and switchTime
+	current   int         // index of the endpoint in use
+	// time of the last switch or failback attempt
+	switchTime time.Time
+
+	// http transport config
+	username string
+	password string
+	headers  map[string]string
+	caFile   string
 }
 
 // New create new json_rpc client with given url
 func newRPC(url string, options ...func(rpc *MonitorRPC)) *MonitorRPC {
 	rpc := &MonitorRPC{
-		url:    url,
-		scheme: "tcp",
+		url:         url,
+		scheme:      schemeTCP,
+		poolSize:    defaultPoolSize,
+		idleTimeout: defaultIdleTimeout,
+		timeout:     defaultTimeout,
+
+		failbackInterval: defaultFailbackInterval,
 	}
 	for _, option := range options {
 		option(rpc)
 	}
+
+	for _, url := range append([]string{rpc.url}, rpc.fallbacks...) {
+		rpc.endpoints = append(rpc.endpoints, rpc.newEndpoint(url))
+	}
 	return rpc
 }
 
+// newEndpoint creates the transport of the url, the scheme of the url wins
+// over the scheme option
+func (rpc *MonitorRPC) newEndpoint(url string) *endpoint {
+	scheme := rpc.scheme
+	switch {
+	case strings.HasPrefix(url, schemeHTTP+"://"):
+		scheme = schemeHTTP
+	case strings.HasPrefix(url, schemeHTTPS+"://"):
+		scheme = schemeHTTPS
+	case scheme == schemeHTTP || scheme == schemeHTTPS:
+		url = scheme + "://" + url
+	}
+
+	if scheme == schemeHTTP || scheme == schemeHTTPS {
+		httpTransport, err := newHTTPTransport(url, rpc)
+		if err != nil {
+			return &endpoint{url: url, transport: &brokenTransport{err: err}}
+		}
+		return &endpoint{url: url, transport: httpTransport}
+	}
+	return &endpoint{url: url, transport: newClientPool(scheme, url, rpc.poolSize, rpc.idleTimeout)}
+}
+
+// WithScheme set the transport, tcp, http or https. A http:// or https:// url overrides it
+func WithScheme(scheme string) func(rpc *MonitorRPC) {
+	return func(rpc *MonitorRPC) {
+		if scheme != "" {
+			rpc.scheme = strings.ToLower(scheme)
+		}
+	}
+}
+
+// WithBasicAuth set the basic auth of the http transport
+func WithBasicAuth(username, password string) func(rpc *MonitorRPC) {
+	return func(rpc *MonitorRPC) {
+		rpc.username = username
+		rpc.password = password
+	}
+}
+
+// WithHeaders set the extra headers of the http transport
+func WithHeaders(headers map[string]string) func(rpc *MonitorRPC) {
+	return func(rpc *MonitorRPC) {
+		rpc.headers = headers
+	}
+}
+
+// WithCAFile set the CA certificate file to verify the https node
+func WithCAFile(caFile string) func(rpc *MonitorRPC) {
+	return func(rpc *MonitorRPC) {
+		rpc.caFile = caFile
+	}
+}
+
+// WithPoolSize set the max idle connections kept to the node
+func WithPoolSize(size int) func(rpc *MonitorRPC) {
+	return func(rpc *MonitorRPC) {
+		if size > 0 {
+			rpc.poolSize = size
+		}
+	}
+}
+
+// WithIdleTimeout set the time after which an idle connection is redialed
+func WithIdleTimeout(idleTimeout time.Duration) func(rpc *MonitorRPC) {
+	return func(rpc *MonitorRPC) {
+		rpc.idleTimeout = idleTimeout
+	}
+}
+
+// WithFallbacks set the urls of the standby endpoints of the same node, tried
+// in order when the current endpoint fails
+func WithFallbacks(urls ...string) func(rpc *MonitorRPC) {
+	return func(rpc *MonitorRPC) {
+		rpc.fallbacks = urls
+	}
+}
+
+// WithFailbackInterval set the interval to try the primary endpoint again
+// after a failover, 0 means stay on the standby endpoint
+func WithFailbackInterval(interval time.Duration) func(rpc *MonitorRPC) {
+	return func(rpc *MonitorRPC) {
+		rpc.failbackInterval = interval
+	}
+}
+
+// WithFailover set the function called with the urls when the current endpoint changes
+func WithFailover(onFailover func(from, to string)) func(rpc *MonitorRPC) {
+	return func(rpc *MonitorRPC) {
+		rpc.onFailover = onFailover
+	}
+}
+
+// WithTimeout set the timeout of a call on one endpoint, 0 means no timeout
+func WithTimeout(timeout time.Duration) func(rpc *MonitorRPC) {
+	return func(rpc *MonitorRPC) {
+		rpc.timeout = timeout
+	}
+}
+
+// WithDebug log every request and response
+func WithDebug(debug bool) func(rpc *MonitorRPC) {
+	return func(rpc *MonitorRPC) {
+		rpc.Debug = debug
+	}
+}
+
 // NewSeeleRPC create new json_rpc
 func NewSeeleRPC(url string, options ...func(rpc *MonitorRPC)) *MonitorRPC {
 	return newRPC(url, options...)
 }
 
-func (rpc *MonitorRPC) call(serviceMethod string, args interface{}, reply interface{}) error {
-	conn, err := Dial(rpc.scheme, rpc.url)
-	defer func() {
-		if conn != nil {
-			conn.Close()
+// Close closes the pooled connections of all the endpoints
+func (rpc *MonitorRPC) Close() {
+	for _, endpoint := range rpc.endpoints {
+		endpoint.transport.close()
+	}
+}
+
+// CurrentEndpoint returns the url of the endpoint in use
+func (rpc *MonitorRPC) CurrentEndpoint() string {
+	rpc.mu.Lock()
+	defer rpc.mu.Unlock()
+	return rpc.endpoints[rpc.current].url
+}
+
+// firstEndpoint returns the index of the endpoint to try first, the primary
+// endpoint once every failbackInterval after a failover.
+func (rpc *MonitorRPC) firstEndpoint() int {
+	rpc.mu.Lock()
+	defer rpc.mu.Unlock()
+	if rpc.current != 0 && rpc.failbackInterval > 0 && time.Since(rpc.switchTime) >= rpc.failbackInterval {
+		rpc.switchTime = time.Now()
+		return 0
+	}
+	return rpc.current
+}
+
+// use makes the endpoint at index the current endpoint
+func (rpc *MonitorRPC) use(index int) {
+	rpc.mu.Lock()
+	from := rpc.current
+	if from == index {
+		rpc.mu.Unlock()
+		return
+	}
+	rpc.current = index
+	rpc.switchTime = time.Now()
+	rpc.mu.Unlock()
+
+	failovers.Inc(rpc.endpoints[from].url, rpc.endpoints[index].url)
+	if rpc.onFailover != nil {
+		rpc.onFailover(rpc.endpoints[from].url, rpc.endpoints[index].url)
+	}
+}
+
+// do runs the request on the endpoints in order from the current one until an
+// endpoint answers, each try is bounded by the timeout. It fails over on the
+// connection errors and the timeouts, not on the errors of the node.
+func (rpc *MonitorRPC) do(ctx context.Context, method string, request func(ctx context.Context, t transport) error) error {
+	first := rpc.firstEndpoint()
+	var err error
+	for i := 0; i < len(rpc.endpoints); i++ {
+		index := (first + i) % len(rpc.endpoints)
+		endpoint := rpc.endpoints[index]
+
+		var (
+			tryCtx context.Context
+			cancel context.CancelFunc
+		)
+		if rpc.timeout > 0 {
+			tryCtx, cancel = context.WithTimeout(ctx, rpc.timeout)
+		} else {
+			tryCtx, cancel = context.WithCancel(ctx)
+		}
+		start := time.Now()
+		err = request(tryCtx, endpoint.transport)
+		timedOut := contextError(tryCtx) != nil
+		cancel()
+		callDuration.Observe(time.Since(start).Seconds(), endpoint.url, method)
+		if err != nil {
+			callErrors.Inc(endpoint.url, method)
 		}
-	}()
 
+		_, broken := endpoint.transport.(*brokenTransport)
+		if err == nil || !(timedOut || broken || isConnError(err)) {
+			rpc.use(index)
+			return err
+		}
+		if ctx.Err() != nil {
+			return err
+		}
+	}
+	return err
+}
+
+func (rpc *MonitorRPC) call(serviceMethod string, args interface{}, reply interface{}) error {
+	return rpc.callContext(context.Background(), serviceMethod, args, reply)
+}
+
+func (rpc *MonitorRPC) callContext(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	err := rpc.do(ctx, serviceMethod, func(ctx context.Context, t transport) error {
+		return t.call(ctx, serviceMethod, args, &reply)
+	})
 	if err != nil {
 		return err
 	}
+	if rpc.Debug {
+		logs.Debug("%s\nRequest: %v\nResponse: %v\n", serviceMethod, args, &reply)
+	}
+	return nil
+}
 
-	err = conn.Call(serviceMethod, args, &reply)
+// batchContext sends the elems in one batch request, the errors of the calls
+// are set in the elems.
+func (rpc *MonitorRPC) batchContext(ctx context.Context, elems []BatchElem) error {
+	err := rpc.do(ctx, "batch", func(ctx context.Context, t transport) error {
+		return t.batch(ctx, elems)
+	})
 	if err != nil {
 		return err
 	}
 	if rpc.Debug {
-		logs.Debug("%s\nRequest: %v\nResponse: %v\n", serviceMethod, args, &reply)
+		for _, elem := range elems {
+			logs.Debug("%s\nRequest: %v\nResponse: %v\nError: %v\n", elem.Method, elem.Args, elem.Result, elem.Error)
+		}
 	}
 	return nil
 }
+
+// brokenTransport fails every call with the error of creating the transport
+type brokenTransport struct {
+	err error
+}
+
+func (t *brokenTransport) call(ctx context.Context, serviceMethod string, args interface{}, reply interface{}) error {
+	return t.err
+}
+
+func (t *brokenTransport) batch(ctx context.Context, elems []BatchElem) error {
+	return t.err
+}
+
+func (t *brokenTransport) close() {}
@@ -0,0 +1,49 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package server
+
+import (
+	"context"
+	"strings"
+
+	"golang.org/x/sync/errgroup"
+
+	"github.com/seeleteam/monitor-api/config"
+	"github.com/seeleteam/monitor-api/core/logs"
+	"github.com/seeleteam/monitor-api/ws"
+)
+
+// watchConfig applies the live settings of the changed config files until the ctx is done
+func watchConfig(ctx context.Context, g *errgroup.Group) {
+	interval := config.SeeleConfig.ServerConfig.ReloadInterval
+	if interval <= 0 {
+		return
+	}
+	watcher := config.NewWatcher(interval)
+	g.Go(func() error {
+		watcher.Run(ctx, applyReload)
+		return nil
+	})
+}
+
+// applyReload applies the log level, the report periods, the error report
+// times and the monitor servers, the other settings wait for a restart
+func applyReload(reload *config.Reload, err error) {
+	if err != nil {
+		logs.Error("config reload failed, the config is unchanged, err %v", err)
+		return
+	}
+	for _, setting := range reload.Restart {
+		logs.Warn("config %v changed, restart to apply it", setting)
+	}
+	if !reload.Changed() {
+		return
+	}
+	reload.Apply()
+	logs.GetLogger().SetLevel(reload.Config.ServerConfig.LogLevel)
+	ws.Reload(reload.Config.ServerConfig.WebSocketConfig)
+	logs.Info("config reloaded, changed %v, monitor servers changed %v", strings.Join(reload.Live, ", "), reload.ServersChanged)
+}
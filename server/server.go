@@ -6,10 +6,13 @@
 package server
 
 import (
-	"log"
+	"context"
+	"fmt"
 	"os"
+	"sync/atomic"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	"golang.org/x/sync/errgroup"
 
 	"github.com/seeleteam/monitor-api/config"
@@ -19,20 +22,31 @@ import (
 	"github.com/seeleteam/monitor-api/ws"
 )
 
-// Start WithErrorGroup
-func Start(g *errgroup.Group) {
+// Start WithErrorGroup, the http server and the web socket services run in
+// the group until the ctx is done
+func Start(ctx context.Context, g *errgroup.Group) {
 	// init the logger
 	logs.NewLogger()
 
 	monitorServer := core.GetServer(g)
 	monitorServer.RunServer()
+	g.Go(func() error {
+		<-ctx.Done()
+		logs.Infoln("shutting down the http server")
+		return monitorServer.Shutdown(context.Background())
+	})
+	watchConfig(ctx, g)
 
 	// start RPCService, if enableWs = true
 	enableWs := config.SeeleConfig.ServerConfig.EnableWebSocket
 	if enableWs {
 		logs.Infoln("will start web socket")
-		time.Sleep(5 * time.Second)
-		startWsService()
+		select {
+		case <-time.After(5 * time.Second):
+		case <-ctx.Done():
+			return
+		}
+		startWsService(ctx, g)
 	} else {
 		logs.Errorln("web socket start failed, EnableWebSocket is false")
 		os.Exit(-1)
@@ -40,21 +54,64 @@ func Start(g *errgroup.Group) {
 
 }
 
-func startWsService() {
+func startWsService(ctx context.Context, g *errgroup.Group) {
 	enableRPC := config.SeeleConfig.ServerConfig.EnableRPC
 	if !enableRPC {
 		logs.Fatalln("start RPC Service failed, EnableRPC is false")
 		return
 	}
 
-	rpcURL := config.SeeleConfig.ServerConfig.RPCConfig.URL
-	rpcSeeleRPC := rpc.NewSeeleRPC(rpcURL)
-
 	wsURL := config.SeeleConfig.ServerConfig.WebSocketConfig.WsURL
-	service, err := ws.New(wsURL, rpcSeeleRPC)
-	if err != nil {
-		log.Fatal(err)
+	rpcConfig := config.SeeleConfig.ServerConfig.RPCConfig
+
+	// every node has its own rpc and web socket service, a node failing to
+	// start is logged and the others keep running unless every node failed
+	nodes := config.SeeleConfig.ServerConfig.GetNodeConfigs()
+	var failed int32
+	nodeFailed := func(err error) error {
+		if int(atomic.AddInt32(&failed, 1)) < len(nodes) {
+			logs.Error("%v", err)
+			return nil
+		}
+		return fmt.Errorf("every monitored node failed, last %v", err)
+	}
+	for _, node := range nodes {
+		node := node
+		g.Go(func() error {
+			logs.Info("start monitor node %v, rpc %v", node.Name, node.RPCURL)
+			urls := node.RPCURLs()
+			if len(urls) == 0 {
+				return nodeFailed(fmt.Errorf("node %v: RPCURL should not be empty", node.Name))
+			}
+			nodeLog := logs.WithFields(logrus.Fields{"node": node.Name})
+			rpcSeeleRPC := rpc.NewSeeleRPC(urls[0],
+				rpc.WithFallbacks(urls[1:]...),
+				rpc.WithFailbackInterval(rpcConfig.FailbackInterval),
+				rpc.WithFailover(func(from, to string) {
+					nodeLog.Warn("rpc endpoint switched from %v to %v", from, to)
+				}),
+				rpc.WithPoolSize(rpcConfig.PoolSize),
+				rpc.WithIdleTimeout(rpcConfig.IdleTimeout),
+				rpc.WithTimeout(rpcConfig.Timeout),
+				rpc.WithDebug(rpcConfig.Debug),
+				rpc.WithScheme(rpcConfig.Scheme),
+				rpc.WithBasicAuth(rpcConfig.Username, rpcConfig.Password),
+				rpc.WithHeaders(rpcConfig.Headers),
+				rpc.WithCAFile(rpcConfig.CAFile))
+			defer rpcSeeleRPC.Close()
+			service, err := ws.New(wsURL, rpcSeeleRPC,
+				ws.WithContext(ctx),
+				ws.WithName(node.Name),
+				ws.WithInstanceName(node.InstanceName),
+				ws.WithShard(node.Shard))
+			if err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
+				return nodeFailed(fmt.Errorf("node %v: %v", node.Name, err))
+			}
+			service.Start(ctx)
+			return nil
+		})
 	}
-	//go service.Start()
-	service.Start()
 }
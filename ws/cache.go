@@ -0,0 +1,117 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/seeleteam/monitor-api/rpc"
+)
+
+// cacheTickers is how many ticker periods the cached node data stays fresh
+const cacheTickers = 2
+
+// nodeCache keeps the node data of the last reports for the REST api
+type nodeCache struct {
+	mu        sync.Mutex
+	info      *rpc.NodeInfo
+	infoTime  time.Time
+	stats     *rpc.NodeStats
+	statsTime time.Time
+	block     *rpc.CurrentBlock
+	blockTime time.Time
+}
+
+func (c *nodeCache) setInfo(info *rpc.NodeInfo) {
+	c.mu.Lock()
+	c.info, c.infoTime = info, time.Now()
+	c.mu.Unlock()
+}
+
+func (c *nodeCache) setStats(stats *rpc.NodeStats) {
+	c.mu.Lock()
+	c.stats, c.statsTime = stats, time.Now()
+	c.mu.Unlock()
+}
+
+func (c *nodeCache) setBlock(block *rpc.CurrentBlock) {
+	c.mu.Lock()
+	c.block, c.blockTime = block, time.Now()
+	c.mu.Unlock()
+}
+
+// fresh reports whether the data cached at t is younger than cacheTickers periods
+func fresh(t time.Time, period time.Duration) bool {
+	return !t.IsZero() && time.Since(t) <= cacheTickers*period
+}
+
+// NodeInfo returns the node info of the last report, or from rpc if stale
+func (s *Service) NodeInfo(ctx context.Context) (*rpc.NodeInfo, error) {
+	s.cache.mu.Lock()
+	info, infoTime := s.cache.info, s.cache.infoTime
+	s.cache.mu.Unlock()
+	fullInterval, _ := s.intervals()
+	if info != nil && fresh(infoTime, fullInterval) {
+		return info, nil
+	}
+
+	info, err := s.rpc.NodeInfoContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.setInfo(info)
+	return info, nil
+}
+
+// NodeStats returns the node stats of the last report, or from rpc if stale
+func (s *Service) NodeStats(ctx context.Context) (*rpc.NodeStats, error) {
+	s.cache.mu.Lock()
+	stats, statsTime := s.cache.stats, s.cache.statsTime
+	s.cache.mu.Unlock()
+	fullInterval, _ := s.intervals()
+	if stats != nil && fresh(statsTime, fullInterval) {
+		return stats, nil
+	}
+
+	stats, err := s.rpc.NodeStatsContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.setStats(stats)
+	return stats, nil
+}
+
+// LatestBlock returns the current block of the last report, or from rpc if stale
+func (s *Service) LatestBlock(ctx context.Context) (*rpc.CurrentBlock, error) {
+	s.cache.mu.Lock()
+	block, blockTime := s.cache.block, s.cache.blockTime
+	s.cache.mu.Unlock()
+	_, blockInterval := s.intervals()
+	if block != nil && fresh(blockTime, blockInterval) {
+		return block, nil
+	}
+
+	block, err := s.rpc.CurrentBlockContext(ctx, -1, true)
+	if err != nil {
+		return nil, err
+	}
+	s.cache.setBlock(block)
+	return block, nil
+}
+
+// Block returns the block at the height, the cached current block if it is
+// at the height, otherwise from rpc
+func (s *Service) Block(ctx context.Context, height uint64) (*rpc.CurrentBlock, error) {
+	s.cache.mu.Lock()
+	block := s.cache.block
+	s.cache.mu.Unlock()
+	if block != nil && block.Height == height {
+		return block, nil
+	}
+	return s.rpc.CurrentBlockContext(ctx, int64(height), true)
+}
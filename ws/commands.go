@@ -0,0 +1,181 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/seeleteam/monitor-api/protocol"
+)
+
+// commandHandler answers a command of the monitor server, a returned error is
+// acknowledged to the server with an error emit
+type commandHandler func(s *Service, conn *websocket.Conn, msg *protocol.Message) error
+
+// commands are the handlers of the commands of the monitor server by emit name
+var commands = map[string]commandHandler{
+	protocol.EventNodePong:     handlePong,
+	protocol.EventHistory:      handleHistory,
+	protocol.EventStatsRequest: handleStatsRequest,
+	protocol.EventReady:        handleReady,
+	protocol.EventSetInterval:  handleSetInterval,
+}
+
+// errHistoryBusy rejects a history request while another is served
+var errHistoryBusy = errors.New("history request in progress")
+
+// agentEvents are the emits of the agent and its error acknowledgements, a
+// server echoing them back must not get an error emit, it would be echoed again
+var agentEvents = map[string]bool{
+	protocol.EventHello:       true,
+	protocol.EventNodeInfo:    true,
+	protocol.EventStats:       true,
+	protocol.EventBlock:       true,
+	protocol.EventLatency:     true,
+	protocol.EventReorg:       true,
+	protocol.EventNodePing:    true,
+	protocol.EventError:       true,
+	protocol.EventShardChange: true,
+	protocol.EventGoodbye:     true,
+}
+
+// dispatch runs the handler of the command, the unknown and failed commands
+// are answered with an error emit and the connection is kept, the echoed emits
+// of the agent are only logged
+func (s *Service) dispatch(conn *websocket.Conn, msg *protocol.Message) {
+	if agentEvents[msg.Event] {
+		s.log.Debug("ignore %v emit from the monitor server", msg.Event)
+		return
+	}
+	handler, ok := commands[msg.Event]
+	if !ok {
+		s.commandFailed(conn, msg.Event, fmt.Errorf("unknown command %v", msg.Event))
+		return
+	}
+	if err := handler(s, conn, msg); err != nil {
+		s.commandFailed(conn, msg.Event, err)
+	}
+}
+
+// commandFailed logs the error of the command and sends it to the monitor
+// server, it is not journaled
+func (s *Service) commandFailed(conn *websocket.Conn, command string, err error) {
+	s.log.Warn("command %v of the monitor server failed, err %v", command, err)
+	report, err := protocol.NewMessage(protocol.EventError, &protocol.CommandError{
+		Header:  s.header(),
+		Command: command,
+		Error:   err.Error(),
+	})
+	if err != nil {
+		s.log.Warn("encode command error failed, err %v", err)
+		return
+	}
+	if err = websocket.JSON.Send(conn, report); err != nil {
+		s.log.Warn("command error report failed, err %v", err)
+		return
+	}
+	s.countEmit(report)
+}
+
+// handlePong delivers the pong to the pending latency measurement
+func handlePong(s *Service, conn *websocket.Conn, msg *protocol.Message) error {
+	select {
+	case s.pongCh <- struct{}{}:
+	default:
+		// the ping timed out, the late pong is dropped
+		s.log.Warn("Stats server pong without pending ping")
+	}
+	return nil
+}
+
+// handleHistory answers the history request without blocking the next commands,
+// a request while another is served is rejected, the history reports echoed by
+// the server are ignored
+func handleHistory(s *Service, conn *websocket.Conn, msg *protocol.Message) error {
+	if protocol.IsHistoryReport(msg) {
+		s.log.Debug("ignore history report from the monitor server")
+		return nil
+	}
+	if !s.historyMu.TryLock() {
+		return errHistoryBusy
+	}
+	go func() {
+		defer s.historyMu.Unlock()
+		if err := s.reportHistory(conn, msg); err != nil {
+			s.commandFailed(conn, msg.Event, err)
+		}
+	}()
+	return nil
+}
+
+// handleStatsRequest sends a full report now
+func handleStatsRequest(s *Service, conn *websocket.Conn, msg *protocol.Message) error {
+	s.requestReport()
+	return nil
+}
+
+// handleReady sends a full report now, the monitor server accepted the hello
+func handleReady(s *Service, conn *websocket.Conn, msg *protocol.Message) error {
+	s.log.Debug("monitor server ready")
+	s.requestReport()
+	return nil
+}
+
+// handleSetInterval changes the periods of the full and the block reports
+func handleSetInterval(s *Service, conn *websocket.Conn, msg *protocol.Message) error {
+	var request protocol.SetInterval
+	if err := msg.Decode(&request); err != nil {
+		return err
+	}
+	full := time.Duration(request.Full) * time.Millisecond
+	block := time.Duration(request.Block) * time.Millisecond
+	s.setIntervals(full, block)
+	full, block = s.intervals()
+	s.log.Info("report periods set by the monitor server, full %v, block %v", full, block)
+	return nil
+}
+
+// requestReport asks the loop for a full report, the requests made before
+// the loop takes one are merged
+func (s *Service) requestReport() {
+	select {
+	case s.reportCh <- struct{}{}:
+	default:
+	}
+}
+
+// intervals returns the periods of the full and the block reports
+func (s *Service) intervals() (full, block time.Duration) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	return s.fullEventTickerTime, s.latestBlockEventTickerTime
+}
+
+// setIntervals changes the periods of the reports, a zero period is kept,
+// the loop resets its tickers
+func (s *Service) setIntervals(full, block time.Duration) {
+	s.statusMu.Lock()
+	if full > 0 {
+		s.fullEventTickerTime = full
+	}
+	if block > 0 {
+		s.latestBlockEventTickerTime = block
+	}
+	s.statusMu.Unlock()
+	s.notifyReload()
+}
+
+// notifyReload tells the loop the periods or the monitor servers changed
+func (s *Service) notifyReload() {
+	select {
+	case s.reloadCh <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,48 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/seeleteam/monitor-api/protocol"
+)
+
+func TestSetIntervals(t *testing.T) {
+	s := &Service{
+		reloadCh:                   make(chan struct{}, 1),
+		fullEventTickerTime:        10 * time.Second,
+		latestBlockEventTickerTime: 2 * time.Second,
+	}
+
+	s.setIntervals(30*time.Second, 0)
+	s.setIntervals(0, 5*time.Second)
+	full, block := s.intervals()
+	assert.Equal(t, 30*time.Second, full)
+	assert.Equal(t, 5*time.Second, block)
+	// the changes are merged into one tickers reset
+	assert.Len(t, s.reloadCh, 1)
+
+	for _, event := range []string{protocol.EventNodePong, protocol.EventHistory,
+		protocol.EventStatsRequest, protocol.EventReady, protocol.EventSetInterval} {
+		assert.Contains(t, commands, event)
+	}
+	assert.NotContains(t, commands, protocol.EventStats)
+}
+
+func TestDispatchIgnoresAgentEvents(t *testing.T) {
+	s := &Service{log: newTestLog()}
+	for event := range agentEvents {
+		assert.NotContains(t, commands, event)
+		// no error emit is sent back, the service has no connection to send it
+		assert.NotPanics(t, func() {
+			s.dispatch(nil, newTestMessage(t, event, map[string]interface{}{"id": "node1"}))
+		}, event)
+	}
+}
@@ -0,0 +1,105 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"sync"
+	"time"
+
+	"github.com/seeleteam/monitor-api/protocol"
+)
+
+const (
+	// EventError is the type of the events of the rpc and connection errors
+	EventError = "error"
+
+	eventBufferSize = 64 // events buffered per subscriber, a slow subscriber misses the next events
+)
+
+// Event is an emit produced by a Service, or an error it met
+type Event struct {
+	Node string      `json:"node"`
+	Type string      `json:"type"`
+	Time time.Time   `json:"time"`
+	Data interface{} `json:"data"`
+}
+
+// subscriber receives the events of the node and the types, empty means all
+type subscriber struct {
+	ch    chan Event
+	node  string
+	types map[string]bool
+}
+
+func (sub *subscriber) match(event Event) bool {
+	if sub.node != "" && sub.node != event.Node {
+		return false
+	}
+	return len(sub.types) == 0 || sub.types[event.Type]
+}
+
+// events are the subscribers of the events of all the services
+var events = struct {
+	sync.Mutex
+	subscribers map[*subscriber]struct{}
+}{subscribers: make(map[*subscriber]struct{})}
+
+// Subscribe returns the events of the node and the types, all nodes and all
+// types if empty, and the function to unsubscribe.
+func Subscribe(node string, types ...string) (<-chan Event, func()) {
+	sub := &subscriber{
+		ch:   make(chan Event, eventBufferSize),
+		node: node,
+	}
+	if len(types) > 0 {
+		sub.types = make(map[string]bool, len(types))
+		for _, t := range types {
+			sub.types[t] = true
+		}
+	}
+
+	events.Lock()
+	events.subscribers[sub] = struct{}{}
+	events.Unlock()
+
+	var once sync.Once
+	return sub.ch, func() {
+		once.Do(func() {
+			events.Lock()
+			delete(events.subscribers, sub)
+			events.Unlock()
+		})
+	}
+}
+
+// publish sends the event to the matching subscribers without blocking
+func publish(event Event) {
+	events.Lock()
+	defer events.Unlock()
+	for sub := range events.subscribers {
+		if !sub.match(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+		}
+	}
+}
+
+// publishEmit publishes the emit of the report
+func (s *Service) publishEmit(report *protocol.Message) {
+	var data interface{}
+	if len(report.Payload) != 0 {
+		data = report.Payload
+	}
+	publish(Event{Node: s.name, Type: report.Event, Time: time.Now(), Data: data})
+}
+
+// publishError publishes the error met by the service
+func (s *Service) publishError(err error) {
+	publish(Event{Node: s.name, Type: EventError, Time: time.Now(), Data: map[string]string{"message": err.Error()}})
+}
@@ -0,0 +1,54 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/seeleteam/monitor-api/protocol"
+)
+
+func newTestMessage(t *testing.T, event string, payload interface{}) *protocol.Message {
+	msg, err := protocol.NewMessage(event, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msg
+}
+
+func TestSubscribe(t *testing.T) {
+	s1 := &Service{name: "node1"}
+	s2 := &Service{name: "node2"}
+
+	all, unsubscribeAll := Subscribe("")
+	defer unsubscribeAll()
+	blocks, unsubscribeBlocks := Subscribe("node1", "block", EventError)
+
+	s1.publishEmit(newTestMessage(t, protocol.EventStats, map[string]interface{}{"id": "node1"}))
+	s2.publishEmit(newTestMessage(t, protocol.EventBlock, map[string]interface{}{"id": "node2"}))
+	s1.publishEmit(newTestMessage(t, protocol.EventBlock, map[string]interface{}{"id": "node1"}))
+	s1.publishError(errors.New("rpc down"))
+
+	assert.Len(t, all, 4)
+	assert.Len(t, blocks, 2)
+	event := <-blocks
+	assert.Equal(t, "node1", event.Node)
+	assert.Equal(t, "block", event.Type)
+	assert.Equal(t, json.RawMessage(`{"id":"node1"}`), event.Data)
+	event = <-blocks
+	assert.Equal(t, EventError, event.Type)
+	assert.Equal(t, map[string]string{"message": "rpc down"}, event.Data)
+
+	unsubscribeBlocks()
+	unsubscribeBlocks()
+	s1.publishEmit(&protocol.Message{Event: protocol.EventBlock})
+	assert.Len(t, blocks, 0)
+	assert.Len(t, all, 5)
+}
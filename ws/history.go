@@ -0,0 +1,115 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"fmt"
+	"sort"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/seeleteam/monitor-api/protocol"
+)
+
+const (
+	historyChunkSize = 20   // blocks per history emit
+	maxHistoryBlocks = 1000 // max blocks served for one history request
+)
+
+// historyHeights returns the requested heights in ascending order, without duplicates
+func historyHeights(r *protocol.HistoryRequest) ([]uint64, error) {
+	if err := r.Validate(); err != nil {
+		return nil, err
+	}
+	var heights []uint64
+	if len(r.List) > 0 {
+		seen := make(map[uint64]bool, len(r.List))
+		for _, h := range r.List {
+			if !seen[h] {
+				seen[h] = true
+				heights = append(heights, h)
+			}
+		}
+		sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+	} else {
+		if *r.Max-*r.Min >= maxHistoryBlocks {
+			return nil, fmt.Errorf("history request range too large, max %v blocks", maxHistoryBlocks)
+		}
+		for h := *r.Min; h <= *r.Max; h++ {
+			heights = append(heights, h)
+		}
+	}
+	if len(heights) > maxHistoryBlocks {
+		return nil, fmt.Errorf("history request too large, max %v blocks", maxHistoryBlocks)
+	}
+	return heights, nil
+}
+
+// reportHistory answers the history request of the monitor server with the
+// blocks at the requested heights, in chunks of historyChunkSize, the
+// caller holds historyMu.
+func (s *Service) reportHistory(conn *websocket.Conn, msg *protocol.Message) error {
+	var request protocol.HistoryRequest
+	if err := msg.Decode(&request); err != nil {
+		return err
+	}
+	heights, err := historyHeights(&request)
+	if err != nil {
+		return err
+	}
+
+	chunks := historyChunks(heights)
+	for chunk, chunkHeights := range chunks {
+		blocks, missing := s.historyBlocks(chunkHeights)
+		report, err := protocol.NewMessage(protocol.EventHistory, &protocol.HistoryReport{
+			Header:  s.header(),
+			History: blocks,
+			Missing: missing,
+			Chunk:   chunk + 1,
+			Chunks:  len(chunks),
+		})
+		if err != nil {
+			return err
+		}
+		s.log.Debug("Sending node history chunk %v/%v to monitor, %v missing", chunk+1, len(chunks), len(missing))
+		if err = websocket.JSON.Send(conn, report); err != nil {
+			return err
+		}
+		s.countEmit(report)
+		s.publishEmit(report)
+	}
+	return nil
+}
+
+// historyChunks splits the heights into chunks of historyChunkSize
+func historyChunks(heights []uint64) [][]uint64 {
+	var chunks [][]uint64
+	for len(heights) > historyChunkSize {
+		chunks = append(chunks, heights[:historyChunkSize])
+		heights = heights[historyChunkSize:]
+	}
+	if len(heights) > 0 {
+		chunks = append(chunks, heights)
+	}
+	return chunks
+}
+
+// historyBlocks returns the blocks at the heights, and the heights the rpc
+// failed to return so the monitor server can tell a gap from a short chain
+func (s *Service) historyBlocks(heights []uint64) ([]*protocol.Block, []uint64) {
+	var blocks []*protocol.Block
+	var missing []uint64
+	for _, h := range heights {
+		block, err := s.rpc.CurrentBlock(int64(h), false)
+		if err != nil {
+			s.log.Warn("rpc get history block %v error %v", h, err)
+			missing = append(missing, h)
+			continue
+		}
+		blocks = append(blocks, protocolBlock(block))
+	}
+	return blocks, missing
+}
@@ -0,0 +1,114 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/seeleteam/monitor-api/protocol"
+)
+
+func uint64Ptr(v uint64) *uint64 {
+	return &v
+}
+
+func TestHistoryHeights(t *testing.T) {
+	heights, err := historyHeights(&protocol.HistoryRequest{List: []uint64{5, 3, 5, 1}})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{1, 3, 5}, heights)
+
+	heights, err = historyHeights(&protocol.HistoryRequest{Min: uint64Ptr(10), Max: uint64Ptr(12)})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{10, 11, 12}, heights)
+
+	heights, err = historyHeights(&protocol.HistoryRequest{Min: uint64Ptr(7), Max: uint64Ptr(7)})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{7}, heights)
+
+	// at most maxHistoryBlocks
+	heights, err = historyHeights(&protocol.HistoryRequest{Min: uint64Ptr(1), Max: uint64Ptr(maxHistoryBlocks)})
+	assert.NoError(t, err)
+	assert.Len(t, heights, maxHistoryBlocks)
+	_, err = historyHeights(&protocol.HistoryRequest{Min: uint64Ptr(0), Max: uint64Ptr(maxHistoryBlocks)})
+	assert.EqualError(t, err, "history request range too large, max 1000 blocks")
+
+	list := make([]uint64, maxHistoryBlocks+1)
+	for i := range list {
+		list[i] = uint64(i)
+	}
+	_, err = historyHeights(&protocol.HistoryRequest{List: list})
+	assert.EqualError(t, err, "history request too large, max 1000 blocks")
+	// the duplicates do not count
+	_, err = historyHeights(&protocol.HistoryRequest{List: append(list[:maxHistoryBlocks], 0, 1)})
+	assert.NoError(t, err)
+
+	_, err = historyHeights(&protocol.HistoryRequest{Min: uint64Ptr(5), Max: uint64Ptr(1)})
+	assert.EqualError(t, err, "history request min 5 > max 1")
+	_, err = historyHeights(&protocol.HistoryRequest{Min: uint64Ptr(5)})
+	assert.Error(t, err)
+
+	// the rpc reads the heights above the max int64 as negative
+	_, err = historyHeights(&protocol.HistoryRequest{List: []uint64{1, math.MaxInt64 + 1}})
+	assert.EqualError(t, err, "history request height 9223372036854775808 out of range")
+	_, err = historyHeights(&protocol.HistoryRequest{Min: uint64Ptr(math.MaxUint64 - 1), Max: uint64Ptr(math.MaxUint64)})
+	assert.EqualError(t, err, "history request max 18446744073709551615 out of range")
+	heights, err = historyHeights(&protocol.HistoryRequest{Min: uint64Ptr(math.MaxInt64), Max: uint64Ptr(math.MaxInt64)})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint64{math.MaxInt64}, heights)
+}
+
+func TestHistoryChunks(t *testing.T) {
+	assert.Empty(t, historyChunks(nil))
+
+	heights := make([]uint64, 2*historyChunkSize+1)
+	for i := range heights {
+		heights[i] = uint64(i)
+	}
+	chunks := historyChunks(heights)
+	if assert.Len(t, chunks, 3) {
+		assert.Len(t, chunks[0], historyChunkSize)
+		assert.Len(t, chunks[1], historyChunkSize)
+		assert.Equal(t, []uint64{2 * historyChunkSize}, chunks[2])
+	}
+	assert.Len(t, historyChunks(heights[:historyChunkSize]), 1)
+}
+
+func TestHistoryBlocks(t *testing.T) {
+	client, closeChain := newFakeChain(t, map[uint64]string{1: "a1", 2: "a2", 4: "a4"})
+	defer closeChain()
+	s := &Service{rpc: client, log: newTestLog()}
+
+	blocks, missing := s.historyBlocks([]uint64{1, 2, 3, 4, 5})
+	var hashes []string
+	for _, block := range blocks {
+		hashes = append(hashes, block.HeadHash)
+	}
+	assert.Equal(t, []string{"a1", "a2", "a4"}, hashes)
+	assert.Equal(t, []uint64{3, 5}, missing)
+}
+
+func TestHandleHistoryReport(t *testing.T) {
+	s := &Service{log: newTestLog()}
+	report := newTestMessage(t, protocol.EventHistory, &protocol.HistoryReport{
+		Header: protocol.NewHeader("node1", 1, 1),
+		Chunk:  1,
+		Chunks: 1,
+	})
+	assert.True(t, protocol.IsHistoryReport(report))
+	// an echoed report is not answered, the service has no rpc to serve it
+	assert.NoError(t, handleHistory(s, nil, report))
+
+	request := newTestMessage(t, protocol.EventHistory, &protocol.HistoryRequest{List: []uint64{1}})
+	assert.False(t, protocol.IsHistoryReport(request))
+
+	// a request while another is served is rejected
+	s.historyMu.Lock()
+	assert.Equal(t, errHistoryBusy, handleHistory(s, nil, request))
+	s.historyMu.Unlock()
+}
@@ -0,0 +1,240 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/seeleteam/monitor-api/config"
+)
+
+const (
+	defaultJournalPath = "monitor-api-journal"
+)
+
+var errJournalFull = errors.New("journal is full")
+
+// journalEntry is one emit recorded while the monitor server is unreachable.
+type journalEntry struct {
+	Seq    uint64          `json:"seq"`
+	Time   int64           `json:"time"` // unix nano when the emit was recorded
+	Report json.RawMessage `json:"report"`
+}
+
+// journal is a bounded on-disk queue of emits, one json entry per line.
+// Entries are kept in memory too, the file lets them survive a restart.
+type journal struct {
+	mu sync.Mutex
+
+	path       string
+	maxEntries int
+	maxBytes   int64
+	maxAge     time.Duration
+	dropPolicy string
+
+	seq     uint64
+	size    int64
+	entries []*journalEntry
+}
+
+// newJournal opens the journal file at path, loading the entries left by a
+// previous run.
+func newJournal(path string, maxEntries int, maxBytes int64, maxAge time.Duration, dropPolicy string) (*journal, error) {
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return nil, err
+	}
+	j := &journal{
+		path:       path,
+		maxEntries: maxEntries,
+		maxBytes:   maxBytes,
+		maxAge:     maxAge,
+		dropPolicy: dropPolicy,
+	}
+	if err := j.load(); err != nil {
+		return nil, err
+	}
+	return j, nil
+}
+
+// load reads the existing entries, skipping the broken lines
+func (j *journal) load() error {
+	f, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 16<<20)
+	for scanner.Scan() {
+		var entry journalEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			continue
+		}
+		j.entries = append(j.entries, &entry)
+		j.size += int64(len(scanner.Bytes()) + 1)
+		if entry.Seq > j.seq {
+			j.seq = entry.Seq
+		}
+	}
+	return scanner.Err()
+}
+
+// append records the report at the tail of the journal, applying the size
+// and age limits with the drop policy.
+func (j *journal) append(report interface{}) error {
+	raw, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	entry := &journalEntry{
+		Seq:    j.seq + 1,
+		Time:   time.Now().UnixNano(),
+		Report: raw,
+	}
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+
+	dropped := j.dropExpired()
+	for j.isFull(int64(len(line))) {
+		if j.dropPolicy == config.JournalDropNewest || len(j.entries) == 0 {
+			if dropped > 0 {
+				j.rewrite()
+			}
+			return errJournalFull
+		}
+		j.size -= j.entrySize(j.entries[0])
+		j.entries = j.entries[1:]
+		dropped++
+	}
+
+	j.seq = entry.Seq
+	j.entries = append(j.entries, entry)
+	j.size += int64(len(line))
+	if dropped > 0 {
+		return j.rewrite()
+	}
+
+	f, err := os.OpenFile(j.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(line)
+	return err
+}
+
+// replay sends the recorded entries in order and removes them from the journal.
+// If send fails the unsent entries are kept for the next replay.
+func (j *journal) replay(send func(raw json.RawMessage) error) (int, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	j.dropExpired()
+	sent := 0
+	var err error
+	for _, entry := range j.entries {
+		if err = send(entry.Report); err != nil {
+			break
+		}
+		j.size -= j.entrySize(entry)
+		sent++
+	}
+	j.entries = j.entries[sent:]
+	if len(j.entries) == 0 {
+		j.size = 0
+	}
+	if rewriteErr := j.rewrite(); err == nil {
+		err = rewriteErr
+	}
+	return sent, err
+}
+
+// len returns the number of entries waiting for replay
+func (j *journal) len() int {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return len(j.entries)
+}
+
+// dropExpired drops the entries older than maxAge from the head
+func (j *journal) dropExpired() int {
+	if j.maxAge <= 0 {
+		return 0
+	}
+	deadline := time.Now().Add(-j.maxAge).UnixNano()
+	dropped := 0
+	for dropped < len(j.entries) && j.entries[dropped].Time < deadline {
+		j.size -= j.entrySize(j.entries[dropped])
+		dropped++
+	}
+	j.entries = j.entries[dropped:]
+	return dropped
+}
+
+// isFull reports whether an entry with the given size exceeds the limits
+func (j *journal) isFull(lineSize int64) bool {
+	if j.maxEntries > 0 && len(j.entries)+1 > j.maxEntries {
+		return true
+	}
+	return j.maxBytes > 0 && j.size+lineSize > j.maxBytes
+}
+
+func (j *journal) entrySize(entry *journalEntry) int64 {
+	line, _ := json.Marshal(entry)
+	return int64(len(line) + 1)
+}
+
+// rewrite replaces the journal file with the current entries
+func (j *journal) rewrite() error {
+	if len(j.entries) == 0 {
+		err := os.Remove(j.path)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	tmpPath := j.path + ".tmp"
+	f, err := os.OpenFile(tmpPath, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	for _, entry := range j.entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		w.Write(line)
+		w.WriteByte('\n')
+	}
+	if err = w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, j.path)
+}
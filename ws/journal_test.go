@@ -0,0 +1,114 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/seeleteam/monitor-api/config"
+)
+
+func newTestJournal(t *testing.T, maxEntries int, dropPolicy string) (*journal, string) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "node.journal")
+	j, err := newJournal(path, maxEntries, 0, time.Hour, dropPolicy)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return j, dir
+}
+
+func replayHeights(t *testing.T, j *journal) []float64 {
+	var heights []float64
+	_, err := j.replay(func(raw json.RawMessage) error {
+		var report map[string][]interface{}
+		if err := json.Unmarshal(raw, &report); err != nil {
+			return err
+		}
+		heights = append(heights, report["emit"][1].(float64))
+		return nil
+	})
+	assert.NoError(t, err)
+	return heights
+}
+
+func TestJournalReplayInOrder(t *testing.T) {
+	j, dir := newTestJournal(t, 10, config.JournalDropOldest)
+	defer os.RemoveAll(dir)
+
+	for i := 1; i <= 3; i++ {
+		assert.NoError(t, j.append(map[string][]interface{}{"emit": {"block", i}}))
+	}
+
+	// entries survive a restart
+	reopened, err := newJournal(j.path, 10, 0, time.Hour, config.JournalDropOldest)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, reopened.len())
+	assert.Equal(t, uint64(3), reopened.seq)
+
+	assert.Equal(t, []float64{1, 2, 3}, replayHeights(t, reopened))
+	assert.Equal(t, 0, reopened.len())
+	_, err = os.Stat(j.path)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestJournalDropPolicy(t *testing.T) {
+	oldest, dir := newTestJournal(t, 2, config.JournalDropOldest)
+	defer os.RemoveAll(dir)
+	for i := 1; i <= 3; i++ {
+		assert.NoError(t, oldest.append(map[string][]interface{}{"emit": {"block", i}}))
+	}
+	assert.Equal(t, []float64{2, 3}, replayHeights(t, oldest))
+
+	newest, dir2 := newTestJournal(t, 2, config.JournalDropNewest)
+	defer os.RemoveAll(dir2)
+	for i := 1; i <= 2; i++ {
+		assert.NoError(t, newest.append(map[string][]interface{}{"emit": {"block", i}}))
+	}
+	assert.Equal(t, errJournalFull, newest.append(map[string][]interface{}{"emit": {"block", 3}}))
+	assert.Equal(t, []float64{1, 2}, replayHeights(t, newest))
+}
+
+func TestJournalReplayKeepsUnsent(t *testing.T) {
+	j, dir := newTestJournal(t, 10, config.JournalDropOldest)
+	defer os.RemoveAll(dir)
+	for i := 1; i <= 3; i++ {
+		assert.NoError(t, j.append(map[string][]interface{}{"emit": {"block", i}}))
+	}
+
+	calls := 0
+	sent, err := j.replay(func(raw json.RawMessage) error {
+		calls++
+		if calls == 2 {
+			return errors.New("connection lost")
+		}
+		return nil
+	})
+	assert.Error(t, err)
+	assert.Equal(t, 1, sent)
+	assert.Equal(t, []float64{2, 3}, replayHeights(t, j))
+}
+
+func TestJournalMaxAge(t *testing.T) {
+	j, dir := newTestJournal(t, 10, config.JournalDropOldest)
+	defer os.RemoveAll(dir)
+	j.maxAge = time.Millisecond
+
+	assert.NoError(t, j.append(map[string][]interface{}{"emit": {"block", 1}}))
+	time.Sleep(5 * time.Millisecond)
+	assert.Empty(t, replayHeights(t, j))
+}
@@ -0,0 +1,104 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"math/big"
+	"strconv"
+
+	"github.com/seeleteam/monitor-api/core/metrics"
+	"github.com/seeleteam/monitor-api/protocol"
+	"github.com/seeleteam/monitor-api/rpc"
+)
+
+// the node gauges are labelled with the node name, the shard and the netVersion
+var nodeLabels = []string{"node", "shard", "netVersion"}
+
+var (
+	nodeActive          = metrics.NewGaugeVec("monitor_api_node_active", "1 if the go-seele node is active.", nodeLabels...)
+	nodeSyncing         = metrics.NewGaugeVec("monitor_api_node_syncing", "1 if the go-seele node is syncing.", nodeLabels...)
+	nodeMining          = metrics.NewGaugeVec("monitor_api_node_mining", "1 if the go-seele node is mining.", nodeLabels...)
+	nodePeers           = metrics.NewGaugeVec("monitor_api_node_peers", "Peers of the go-seele node.", nodeLabels...)
+	nodeHashrate        = metrics.NewGaugeVec("monitor_api_node_hashrate", "Hashrate of the go-seele node.", nodeLabels...)
+	nodeBlockHeight     = metrics.NewGaugeVec("monitor_api_node_block_height", "Height of the current block.", nodeLabels...)
+	nodeBlockDifficulty = metrics.NewGaugeVec("monitor_api_node_block_difficulty", "Difficulty of the current block.", nodeLabels...)
+	nodeBlockTxCount    = metrics.NewGaugeVec("monitor_api_node_block_txcount", "Transactions in the current block.", nodeLabels...)
+	nodeLatency         = metrics.NewGaugeVec("monitor_api_node_latency_milliseconds", "Latency to the monitor server.", nodeLabels...)
+
+	nodeGauges = []*metrics.GaugeVec{
+		nodeActive, nodeSyncing, nodeMining, nodePeers, nodeHashrate,
+		nodeBlockHeight, nodeBlockDifficulty, nodeBlockTxCount, nodeLatency,
+	}
+
+	wsReconnects = metrics.NewCounterVec("monitor_api_ws_reconnects_total",
+		"Connections to the monitor server after the first one.", "node")
+	wsEmits = metrics.NewCounterVec("monitor_api_ws_emits_total",
+		"Emits sent to the monitor server.", "node", "emit")
+	wsShardChanges = metrics.NewCounterVec("monitor_api_ws_shard_changes_total",
+		"Connections closed because the node moved to another shard.", "node")
+	wsBackoffSeconds = metrics.NewGaugeVec("monitor_api_ws_backoff_seconds",
+		"Last retry delay, 0 after a stable connection.", "node", "kind")
+)
+
+// the kinds of the backoff gauge
+const (
+	backoffDial = "dial" // web socket dial failures
+	backoffRPC  = "rpc"  // rpc and initial report failures
+)
+
+// metricLabels returns the label values of the node gauges, the gauges of
+// the previous shard or netVersion are removed.
+func (s *Service) metricLabels() []string {
+	labels := []string{
+		s.name,
+		strconv.FormatUint(uint64(s.shard), 10),
+		strconv.FormatUint(s.currentNetVersion, 10),
+	}
+	if s.lastMetricLabels != nil && (labels[1] != s.lastMetricLabels[1] || labels[2] != s.lastMetricLabels[2]) {
+		for _, gauge := range nodeGauges {
+			gauge.DeleteLabel("node", s.name)
+		}
+	}
+	s.lastMetricLabels = labels
+	return labels
+}
+
+func (s *Service) updateStatsMetrics(stats *rpc.NodeStats) {
+	labels := s.metricLabels()
+	nodeActive.Set(boolValue(stats.Active), labels...)
+	nodeSyncing.Set(boolValue(stats.Syncing), labels...)
+	nodeMining.Set(boolValue(stats.Mining), labels...)
+	nodePeers.Set(float64(stats.Peers), labels...)
+	nodeHashrate.Set(float64(stats.Hashrate), labels...)
+}
+
+func (s *Service) updateBlockMetrics(block *rpc.CurrentBlock) {
+	labels := s.metricLabels()
+	nodeBlockHeight.Set(float64(block.Height), labels...)
+	nodeBlockTxCount.Set(float64(block.TxCount), labels...)
+	if block.Difficulty != nil {
+		difficulty, _ := new(big.Float).SetInt(block.Difficulty).Float64()
+		nodeBlockDifficulty.Set(difficulty, labels...)
+	}
+}
+
+func (s *Service) updateLatencyMetrics(latency string) {
+	if value, err := strconv.ParseFloat(latency, 64); err == nil {
+		nodeLatency.Set(value, s.metricLabels()...)
+	}
+}
+
+// countEmit counts the emit sent to the monitor server
+func (s *Service) countEmit(report *protocol.Message) {
+	wsEmits.Inc(s.name, report.Event)
+}
+
+func boolValue(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,63 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// services are the services created in this process, by name
+var services = struct {
+	sync.Mutex
+	byName map[string]*Service
+}{byName: make(map[string]*Service)}
+
+// register makes the service visible to Statuses and Lookup, New registers
+// the service once it is ready to start
+func register(s *Service) {
+	services.Lock()
+	services.byName[s.name] = s
+	services.Unlock()
+}
+
+// Statuses returns the status of the services ordered by name
+func Statuses() []Status {
+	services.Lock()
+	list := make([]*Service, 0, len(services.byName))
+	for _, s := range services.byName {
+		list = append(list, s)
+	}
+	services.Unlock()
+
+	statuses := make([]Status, 0, len(list))
+	for _, s := range list {
+		statuses = append(statuses, s.Status())
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].Name < statuses[j].Name })
+	return statuses
+}
+
+// Lookup returns the service of the node name, an empty name means the only
+// service of the process
+func Lookup(name string) (*Service, error) {
+	services.Lock()
+	defer services.Unlock()
+	if name == "" {
+		if len(services.byName) == 1 {
+			for _, s := range services.byName {
+				return s, nil
+			}
+		}
+		return nil, fmt.Errorf("%v nodes monitored, the node name is required", len(services.byName))
+	}
+	s, ok := services.byName[name]
+	if !ok {
+		return nil, fmt.Errorf("node %v is not monitored", name)
+	}
+	return s, nil
+}
@@ -0,0 +1,39 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"github.com/seeleteam/monitor-api/config"
+)
+
+// Reload applies the live settings of the web socket config to the services,
+// the services whose shard got other monitor servers reconnect
+func Reload(wsConfig *config.WebSocketConfig) {
+	services.Lock()
+	list := make([]*Service, 0, len(services.byName))
+	for _, s := range services.byName {
+		list = append(list, s)
+	}
+	services.Unlock()
+
+	for _, s := range list {
+		s.reload(wsConfig)
+	}
+}
+
+func (s *Service) reload(wsConfig *config.WebSocketConfig) {
+	s.statusMu.Lock()
+	s.reportErrorAfterTimes = wsConfig.ReportErrorAfterTimes
+	s.statusMu.Unlock()
+	s.setIntervals(wsConfig.WsFullEventTickerTime, wsConfig.WsLatestBlockEventTickerTime)
+}
+
+// errorReportTimes returns the rpc errors after which the node is reported down
+func (s *Service) errorReportTimes() int {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	return s.reportErrorAfterTimes
+}
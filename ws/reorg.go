@@ -0,0 +1,186 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"math"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/seeleteam/monitor-api/protocol"
+	"github.com/seeleteam/monitor-api/rpc"
+)
+
+const (
+	defaultBlockRingSize = 64 // recent blocks kept for reorg detection
+)
+
+// blockRecord is the canonical hash seen at a height
+type blockRecord struct {
+	height uint64
+	hash   string
+}
+
+// blockRing keeps the recent blocks ordered by height, the oldest are
+// dropped when the ring is full.
+type blockRing struct {
+	size    int
+	records []blockRecord
+}
+
+func newBlockRing(size int) *blockRing {
+	return &blockRing{size: size}
+}
+
+// add records the hash at the height, the records at or above the height are
+// replaced as they are no longer canonical.
+func (r *blockRing) add(height uint64, hash string) {
+	i := len(r.records)
+	for i > 0 && r.records[i-1].height >= height {
+		i--
+	}
+	r.records = append(r.records[:i], blockRecord{height: height, hash: hash})
+	if len(r.records) > r.size {
+		r.records = r.records[len(r.records)-r.size:]
+	}
+}
+
+// truncate drops the records above the height
+func (r *blockRing) truncate(height uint64) {
+	i := len(r.records)
+	for i > 0 && r.records[i-1].height > height {
+		i--
+	}
+	r.records = r.records[:i]
+}
+
+// hashAt returns the recorded hash at the height
+func (r *blockRing) hashAt(height uint64) (string, bool) {
+	for i := len(r.records) - 1; i >= 0; i-- {
+		if r.records[i].height == height {
+			return r.records[i].hash, true
+		}
+		if r.records[i].height < height {
+			break
+		}
+	}
+	return "", false
+}
+
+// latest returns the highest recorded block
+func (r *blockRing) latest() (blockRecord, bool) {
+	if len(r.records) == 0 {
+		return blockRecord{}, false
+	}
+	return r.records[len(r.records)-1], true
+}
+
+// oldest returns the lowest recorded block
+func (r *blockRing) oldest() (blockRecord, bool) {
+	if len(r.records) == 0 {
+		return blockRecord{}, false
+	}
+	return r.records[0], true
+}
+
+// detectReorg compares the block with the recent blocks, if the canonical hash
+// changed it walks back to the fork point and reports a reorg to the monitor.
+// It returns true if the chain was reorganized.
+func (s *Service) detectReorg(conn *websocket.Conn, block *rpc.CurrentBlock) (bool, error) {
+	latest, ok := s.recentBlocks.latest()
+	if !ok {
+		s.recentBlocks.add(block.Height, block.HeadHash)
+		return false, nil
+	}
+	oldest, _ := s.recentBlocks.oldest()
+
+	changed := false
+	if hash, ok := s.recentBlocks.hashAt(block.Height); ok {
+		// same or lower height, the hash changed or the head went back
+		changed = hash != block.HeadHash || block.Height < latest.height
+	} else if block.Height < oldest.height {
+		changed = true
+	} else if block.Height == latest.height+1 && block.ParentHash != "" {
+		changed = block.ParentHash != latest.hash
+	} else if block.Height > latest.height {
+		// jumped over some blocks, check our latest block is still canonical
+		canonical, err := s.rpc.CurrentBlock(int64(latest.height), false)
+		if err != nil {
+			s.log.Warn("rpc get block %v for reorg detection error %v", latest.height, err)
+		} else {
+			changed = canonical.HeadHash != latest.hash
+		}
+	}
+	if !changed {
+		s.recentBlocks.add(block.Height, block.HeadHash)
+		return false, nil
+	}
+
+	// if the fork is deeper than the ring, the depth is the lower bound
+	forkHeight, _ := s.findForkPoint(block)
+	depth := latest.height - forkHeight
+	s.recentBlocks.truncate(forkHeight)
+	s.recentBlocks.add(block.Height, block.HeadHash)
+
+	s.log.Warn("chain reorganized at %v, depth %v, old head %v, new head %v(%v)", forkHeight, depth, latest.hash, block.HeadHash, block.Height)
+	return true, s.emit(conn, protocol.EventReorg, &protocol.ReorgReport{
+		Header: s.header(),
+		Reorg: &protocol.Reorg{
+			Depth:      depth,
+			ForkHeight: forkHeight,
+			Height:     block.Height,
+			OldHash:    latest.hash,
+			NewHash:    block.HeadHash,
+		},
+	})
+}
+
+// findForkPoint walks back from the block through seele_getBlockByHeight until
+// the canonical hash matches the recorded one.
+func (s *Service) findForkPoint(block *rpc.CurrentBlock) (uint64, bool) {
+	latest, _ := s.recentBlocks.latest()
+	oldest, _ := s.recentBlocks.oldest()
+
+	height := latest.height
+	if block.Height < height {
+		height = block.Height
+	}
+	for ; height >= oldest.height; height-- {
+		recorded, ok := s.recentBlocks.hashAt(height)
+		if !ok {
+			if height == 0 {
+				break
+			}
+			continue
+		}
+
+		canonicalHash := block.HeadHash
+		if height != block.Height {
+			if height > math.MaxInt64 {
+				// the rpc reads a negative height as the latest block
+				s.log.Warn("fork point height %v out of range", height)
+				break
+			}
+			canonical, err := s.rpc.CurrentBlock(int64(height), false)
+			if err != nil {
+				s.log.Warn("rpc get block %v for fork point error %v", height, err)
+				break
+			}
+			canonicalHash = canonical.HeadHash
+		}
+		if canonicalHash == recorded {
+			return height, true
+		}
+		if height == 0 {
+			break
+		}
+	}
+
+	if oldest.height == 0 {
+		return 0, false
+	}
+	return oldest.height - 1, false
+}
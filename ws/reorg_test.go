@@ -0,0 +1,183 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/seeleteam/monitor-api/config"
+	"github.com/seeleteam/monitor-api/core/logs"
+	"github.com/seeleteam/monitor-api/rpc"
+)
+
+// newTestLog returns a logger for the services of the tests
+func newTestLog() *logs.Entry {
+	if logs.GetLogger() == nil {
+		if config.SeeleConfig == nil {
+			config.SeeleConfig = &config.Config{ServerConfig: &config.ServerConfig{
+				LogLevel:     logrus.PanicLevel,
+				EngineConfig: &config.EngineConfig{},
+			}}
+		}
+		logs.NewLogger()
+	}
+	return logs.WithFields(logrus.Fields{"node": "test"})
+}
+
+// newFakeChain serves seele_getBlockByHeight over http from the hashes by height
+func newFakeChain(t *testing.T, hashes map[uint64]string) (*rpc.MonitorRPC, func()) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method string        `json:"method"`
+			Params []interface{} `json:"params"`
+			ID     uint64        `json:"id"`
+		}
+		decoder := json.NewDecoder(r.Body)
+		decoder.UseNumber()
+		if err := decoder.Decode(&req); err != nil || req.Method != "seele_getBlockByHeight" || len(req.Params) == 0 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		number, _ := req.Params[0].(json.Number)
+		height, _ := number.Int64()
+		hash, ok := hashes[uint64(height)]
+		if !ok {
+			json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": nil})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": map[string]interface{}{
+			"hash": hash,
+			"header": map[string]interface{}{
+				"Height":            height,
+				"CreateTimestamp":   0,
+				"Difficulty":        1,
+				"PreviousBlockHash": hashes[uint64(height-1)],
+			},
+		}})
+	}))
+	client := rpc.NewSeeleRPC(server.URL)
+	return client, func() {
+		client.Close()
+		server.Close()
+	}
+}
+
+func TestBlockRing(t *testing.T) {
+	ring := newBlockRing(3)
+	ring.add(1, "a1")
+	ring.add(2, "a2")
+	ring.add(3, "a3")
+	ring.add(4, "a4")
+
+	oldest, _ := ring.oldest()
+	assert.Equal(t, uint64(2), oldest.height)
+	_, ok := ring.hashAt(1)
+	assert.False(t, ok)
+
+	// a block at a known height replaces it and everything above
+	ring.add(3, "b3")
+	latest, _ := ring.latest()
+	assert.Equal(t, blockRecord{height: 3, hash: "b3"}, latest)
+	hash, ok := ring.hashAt(2)
+	assert.True(t, ok)
+	assert.Equal(t, "a2", hash)
+
+	ring.truncate(2)
+	latest, _ = ring.latest()
+	assert.Equal(t, uint64(2), latest.height)
+}
+
+func TestDetectReorg(t *testing.T) {
+	for name, test := range map[string]struct {
+		ring      []blockRecord     // the recent blocks, ring size 3
+		chain     map[uint64]string // the canonical chain of the node
+		block     rpc.CurrentBlock  // the new block
+		reorg     bool
+		fork      uint64 // the ring latest after the reorg is the new block, the fork below it
+		forkFound bool
+	}{
+		"next block": {
+			ring:  []blockRecord{{10, "a10"}, {11, "a11"}, {12, "a12"}},
+			chain: map[uint64]string{12: "a12", 13: "a13"},
+			block: rpc.CurrentBlock{Height: 13, HeadHash: "a13", ParentHash: "a12"},
+		},
+		"same height hash change": {
+			ring:      []blockRecord{{10, "a10"}, {11, "a11"}, {12, "a12"}},
+			chain:     map[uint64]string{11: "a11", 12: "b12"},
+			block:     rpc.CurrentBlock{Height: 12, HeadHash: "b12", ParentHash: "a11"},
+			reorg:     true,
+			fork:      11,
+			forkFound: true,
+		},
+		"parent hash mismatch": {
+			ring:      []blockRecord{{10, "a10"}, {11, "a11"}, {12, "a12"}},
+			chain:     map[uint64]string{11: "a11", 12: "b12", 13: "b13"},
+			block:     rpc.CurrentBlock{Height: 13, HeadHash: "b13", ParentHash: "b12"},
+			reorg:     true,
+			fork:      11,
+			forkFound: true,
+		},
+		"head lower than the last head": {
+			ring:      []blockRecord{{10, "a10"}, {11, "a11"}, {12, "a12"}},
+			chain:     map[uint64]string{10: "a10", 11: "a11"},
+			block:     rpc.CurrentBlock{Height: 11, HeadHash: "a11", ParentHash: "a10"},
+			reorg:     true,
+			fork:      11,
+			forkFound: true,
+		},
+		"fork deeper than the ring": {
+			ring:  []blockRecord{{10, "a10"}, {11, "a11"}, {12, "a12"}},
+			chain: map[uint64]string{9: "b9", 10: "b10", 11: "b11", 12: "b12", 13: "b13"},
+			block: rpc.CurrentBlock{Height: 13, HeadHash: "b13", ParentHash: "b12"},
+			reorg: true,
+			fork:  9,
+		},
+		"fork deeper than the ring at genesis": {
+			ring:  []blockRecord{{0, "a0"}, {1, "a1"}},
+			chain: map[uint64]string{0: "b0", 1: "b1", 2: "b2"},
+			block: rpc.CurrentBlock{Height: 2, HeadHash: "b2", ParentHash: "b1"},
+			reorg: true,
+			fork:  0,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			client, closeChain := newFakeChain(t, test.chain)
+			defer closeChain()
+			s := &Service{rpc: client, log: newTestLog(), recentBlocks: newBlockRing(3)}
+			for _, record := range test.ring {
+				s.recentBlocks.add(record.height, record.hash)
+			}
+
+			block := test.block
+			if test.reorg {
+				fork, found := s.findForkPoint(&block)
+				assert.Equal(t, test.fork, fork)
+				assert.Equal(t, test.forkFound, found)
+			}
+
+			var reorg bool
+			assert.NotPanics(t, func() {
+				// without connection the reorg emit fails
+				reorg, _ = s.detectReorg(nil, &block)
+			})
+			assert.Equal(t, test.reorg, reorg)
+			newLatest, _ := s.recentBlocks.latest()
+			assert.Equal(t, blockRecord{height: block.Height, hash: block.HeadHash}, newLatest)
+			if test.forkFound {
+				// the fork point is kept, the blocks above it are replaced
+				hash, ok := s.recentBlocks.hashAt(test.fork)
+				assert.True(t, ok)
+				assert.Equal(t, test.chain[test.fork], hash)
+			}
+		})
+	}
+}
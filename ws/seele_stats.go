@@ -6,72 +6,129 @@
 package ws
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"math/big"
-	"net"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strconv"
-	"strings"
+	"sync"
 	"time"
 
+	"github.com/sirupsen/logrus"
 	"golang.org/x/net/websocket"
 
 	"github.com/seeleteam/monitor-api/config"
 	"github.com/seeleteam/monitor-api/core/logs"
+	"github.com/seeleteam/monitor-api/core/utils"
+	"github.com/seeleteam/monitor-api/protocol"
 	"github.com/seeleteam/monitor-api/rpc"
 )
 
+const (
+	defaultServiceName = "default" // name of the service when only one node is monitored
+)
+
 // Service implements an Seele monitor reporting daemon that pushes local
 // chain statistics up to a monitoring server.
 type Service struct {
 	rpc *rpc.MonitorRPC // json rpc
-
-	hostname string // hostname of the node to display on the monitoring page
-	node     string // Name of the node to display on the monitoring page
-	pass     string // Password to authorize access to the monitoring page
-	host     string // Remote address of the monitoring service
-	port     int    // monitor api port
-	shard    uint   // shard number
-	wsRouter string // websocket base path
-	wsPath   string // websocket path ex: {host:port}+{wsRouter}
-
-	pongCh                     chan struct{} // Pong notifications are fed into this channel
-	fullEventTickerTime        time.Duration
-	latestBlockEventTickerTime time.Duration
-	delayReConnTime            time.Duration //delay send msg to monitor when web socket server is not be connected
-	delaySendTime              time.Duration //delay send msg to monitor when rpc server is not be connected
-	latestBlockHeight          uint64        // record the latest block height, if rpc get the same block abort send
-	currentBlockHeight         uint64        // record the current block height, if rpc get the same block abort send
-	reportErrorAfterTimes      int           // report the error occur times (currentErrorTimes) when error occur over the special times
+	log *logs.Entry     // logger with the node name field
+
+	ctx           context.Context // stops the rpc retries of New
+	name          string          // name of the monitored node in this process
+	shardOverride int             // shard to report instead of the node shard, -1 means no override
+
+	hostname string                // hostname of the node to display on the monitoring page
+	node     string                // Name of the node to display on the monitoring page, see setHeader
+	pass     string                // Password to authorize access to the monitoring page
+	host     string                // Remote address of the monitoring service
+	port     int                   // monitor api port
+	shard    uint                  // shard number, see setHeader
+	wsRouter string                // websocket base path
+	wsPath   string                // websocket path ex: {host:port}+{wsRouter}
+	server   *config.MonitorServer // monitor server of the connection
+
+	shardServers []config.MonitorServer // monitor servers of the shard dialed last
+
+	pongCh                     chan struct{}  // Pong notifications are fed into this channel
+	reportCh                   chan struct{}  // full report requests of the monitor server
+	reloadCh                   chan struct{}  // the report periods or the monitor servers changed
+	fullEventTickerTime        time.Duration  // protected by statusMu, see intervals
+	latestBlockEventTickerTime time.Duration  // protected by statusMu, see intervals
+	dialBackoff                *utils.Backoff // delay to reconnect when web socket server is not be connected
+	rpcBackoff                 *utils.Backoff // delay to retry when rpc server or the initial report failed
+	backoffResetAfter          time.Duration  // the backoffs are reset after a connection up for this time
+	latestBlockHeight          uint64         // record the latest block height, if rpc get the same block abort send
+	currentBlockHeight         uint64         // record the current block height, if rpc get the same block abort send
+	reportErrorAfterTimes      int            // report the error occur times (currentErrorTimes) when error occur over the special times, protected by statusMu
 	currentErrorTimes          int
-	currentNetVersion          uint64 // current net version(netWorkId)
+	currentNetVersion          uint64 // current net version(netWorkId), see setHeader
+
+	currentBlock *rpc.CurrentBlock // the current block got from rpc
+	recentBlocks *blockRing        // recent (height, hash) for reorg detection
+	journal      *journal          // emits recorded while the monitor server is unreachable, nil if disabled
+	historyMu    sync.Mutex        // held while a history request is served
+	cache        nodeCache         // node data of the last reports for the REST api
+
+	connected        bool     // connected to the monitor server once, the next connections are reconnects
+	lastMetricLabels []string // label values of the node gauges last set
+
+	statusMu sync.Mutex // protects status
+	status   Status     // state published for the readiness check
 }
 
 // New returns a monitoring service ready for stats reporting.
-func New(url string, rpc *rpc.MonitorRPC) (*Service, error) {
+func New(url string, rpc *rpc.MonitorRPC, options ...func(s *Service)) (*Service, error) {
+	s := &Service{
+		rpc:           rpc,
+		ctx:           context.Background(),
+		name:          defaultServiceName,
+		shardOverride: -1,
+	}
+	for _, option := range options {
+		option(s)
+	}
+	s.log = logs.WithFields(logrus.Fields{"node": s.name})
+
+	currentConfig := config.SeeleConfig
+	currentWebSocketConfig := config.WebSocketSettings()
+	if currentWebSocketConfig == nil {
+		return nil, fmt.Errorf("WebSocketConfig is nil")
+	}
+	s.dialBackoff = utils.NewBackoff(currentWebSocketConfig.DelayReConnTime, currentWebSocketConfig.BackoffMaxDelay,
+		currentWebSocketConfig.BackoffMultiplier, currentWebSocketConfig.BackoffJitter)
+	s.rpcBackoff = utils.NewBackoff(currentWebSocketConfig.DelaySendTime, currentWebSocketConfig.BackoffMaxDelay,
+		currentWebSocketConfig.BackoffMultiplier, currentWebSocketConfig.BackoffJitter)
+	s.backoffResetAfter = currentWebSocketConfig.BackoffResetAfter
+
 	// first get RPC NodeInfo and according the Shard choose the ws path
 ErrContinue:
 	info, err := rpc.NodeInfo()
+	s.rpcResult(err)
 	if err != nil {
-		logs.Error("rpc getNodeInfo error %v", err)
-		time.Sleep(5 * time.Second)
+		delay := s.nextDelay(s.rpcBackoff, backoffRPC)
+		s.log.Error("rpc getNodeInfo error(retry after %v, attempt %v) %v", delay, s.rpcBackoff.Attempt(), err)
+		if !sleep(s.ctx, delay) {
+			return nil, s.ctx.Err()
+		}
 		goto ErrContinue
 
 	}
-	shard := info.Shard
+	s.resetDelay(s.rpcBackoff, backoffRPC)
+	shard := s.nodeShard(info)
 
 	version, err := strconv.ParseFloat(info.NetVersion, 10)
 	if err != nil {
-		logs.Warn("netversion err %s", err.Error())
+		s.log.Warn("netversion err %s", err.Error())
 		return nil, err
 	}
-	websocketURL, _ := config.ShardMap[fmt.Sprintf("%v", shard)]
-	if websocketURL == "" {
-		logs.Error("shard config error, shard %v exist error web socket url", shard)
-		return nil, err
+	servers := config.MonitorServers(fmt.Sprintf("%v", shard))
+	if len(servers) == 0 {
+		s.log.Error("shard config error, shard %v exist error web socket url", shard)
+		return nil, fmt.Errorf("no web socket url for shard %v", shard)
 	}
 	// Parse the web socket connection url
 	if url == "" {
@@ -79,11 +136,6 @@ ErrContinue:
 		url = config.SeeleConfig.ServerConfig.Addr
 	}
 
-	currentConfig := config.SeeleConfig
-	currentWebSocketConfig := currentConfig.ServerConfig.WebSocketConfig
-	if currentWebSocketConfig == nil {
-		return nil, fmt.Errorf("WebSocketConfig is nil")
-	}
 	wsRouter := currentWebSocketConfig.WsRouter
 	re := regexp.MustCompile("([^:]*):(.+)")
 	parts := re.FindStringSubmatch(url)
@@ -92,225 +144,358 @@ ErrContinue:
 	}
 	port, err := strconv.Atoi(parts[2])
 	if err != nil {
-		logs.Error("parse url port %v error: %v", port, err)
+		s.log.Error("parse url port %v error: %v", port, err)
 		return nil, err
 	}
-	wsPath := fmt.Sprintf("%s%s", websocketURL, wsRouter)
-	logs.Debug("init shard %v, wsPath is %v", shard, wsPath)
+	wsPath := fmt.Sprintf("%s%s", servers[0].URL, wsRouter)
+	s.log.Debug("init shard %v, wsPath is %v, %v monitor servers", shard, wsPath, len(servers))
 	host := parts[0]
 
-	// name: INSTANCE_NAME || os.hostname()
-	hostname := os.Getenv("INSTANCE_NAME")
+	// name: instance name || INSTANCE_NAME || os.hostname()
+	hostname := s.hostname
+	if hostname == "" {
+		hostname = os.Getenv("INSTANCE_NAME")
+	}
 	if hostname == "" {
 		hostname, _ = os.Hostname()
 	}
 
-	return &Service{
-		rpc:                        rpc,
-		hostname:                   hostname,
-		node:                       hostname,
-		host:                       host,
-		port:                       port,
-		shard:                      shard,
-		wsRouter:                   wsRouter,
-		wsPath:                     wsPath,
-		pongCh:                     make(chan struct{}),
-		fullEventTickerTime:        currentWebSocketConfig.WsFullEventTickerTime,
-		latestBlockEventTickerTime: currentWebSocketConfig.WsLatestBlockEventTickerTime,
-		delayReConnTime:            currentWebSocketConfig.DelayReConnTime,
-		delaySendTime:              currentWebSocketConfig.DelaySendTime,
-		reportErrorAfterTimes:      currentWebSocketConfig.ReportErrorAfterTimes,
-		currentNetVersion:          uint64(version),
-	}, nil
+	var emitJournal *journal
+	if currentWebSocketConfig.EnableJournal {
+		journalName := regexp.MustCompile("[^A-Za-z0-9_.-]").ReplaceAllString(s.name, "_") + ".journal"
+		journalPath := filepath.Join(currentConfig.ServerConfig.EngineConfig.TempFolder, defaultJournalPath, journalName)
+		emitJournal, err = newJournal(journalPath,
+			currentWebSocketConfig.JournalMaxEntries,
+			currentWebSocketConfig.JournalMaxBytes,
+			currentWebSocketConfig.JournalMaxAge,
+			currentWebSocketConfig.JournalDropPolicy)
+		if err != nil {
+			s.log.Error("open journal %v error: %v", journalPath, err)
+			return nil, err
+		}
+		s.log.Debug("journal %v opened with %v emits waiting for replay", journalPath, emitJournal.len())
+	}
+
+	s.hostname = hostname
+	s.node = hostname
+	s.pass = currentWebSocketConfig.WsPass
+	s.host = host
+	s.port = port
+	s.shard = shard
+	s.wsRouter = wsRouter
+	s.wsPath = wsPath
+	s.pongCh = make(chan struct{})
+	s.reportCh = make(chan struct{}, 1)
+	s.reloadCh = make(chan struct{}, 1)
+	s.recentBlocks = newBlockRing(defaultBlockRingSize)
+	s.fullEventTickerTime = currentWebSocketConfig.WsFullEventTickerTime
+	s.latestBlockEventTickerTime = currentWebSocketConfig.WsLatestBlockEventTickerTime
+	s.reportErrorAfterTimes = currentWebSocketConfig.ReportErrorAfterTimes
+	s.currentNetVersion = uint64(version)
+	s.journal = emitJournal
+	register(s)
+	return s, nil
+}
+
+// WithName set the name of the service, used in the logs and the journal file
+func WithName(name string) func(s *Service) {
+	return func(s *Service) {
+		if name != "" {
+			s.name = name
+		}
+	}
+}
+
+// WithInstanceName set the name to display on the monitoring page, overrides INSTANCE_NAME
+func WithInstanceName(instanceName string) func(s *Service) {
+	return func(s *Service) {
+		s.hostname = instanceName
+	}
+}
+
+// WithContext set the context stopping New while it retries the rpc
+func WithContext(ctx context.Context) func(s *Service) {
+	return func(s *Service) {
+		if ctx != nil {
+			s.ctx = ctx
+		}
+	}
+}
+
+// WithShard overrides the shard reported by the node, a negative shard means no override
+func WithShard(shard int) func(s *Service) {
+	return func(s *Service) {
+		s.shardOverride = shard
+	}
+}
+
+// nextDelay returns the next delay of the backoff and publishes it
+func (s *Service) nextDelay(backoff *utils.Backoff, kind string) time.Duration {
+	delay := backoff.Next()
+	wsBackoffSeconds.Set(delay.Seconds(), s.name, kind)
+	return delay
+}
+
+// resetDelay starts the backoff again from the initial delay
+func (s *Service) resetDelay(backoff *utils.Backoff, kind string) {
+	backoff.Reset()
+	wsBackoffSeconds.Set(0, s.name, kind)
+}
+
+// Name returns the name of the monitored node in this process
+func (s *Service) Name() string {
+	return s.name
+}
+
+// nodeShard returns the shard override if set, otherwise the shard of the node
+func (s *Service) nodeShard(info *rpc.NodeInfo) uint {
+	if s.shardOverride >= 0 {
+		return uint(s.shardOverride)
+	}
+	return info.Shard
 }
 
 // Start start the loop for sending statics data to monitor server with web socket
-func (s *Service) Start() {
-	s.loop()
+// until the ctx is done, then it reports the node offline and closes the web socket
+func (s *Service) Start(ctx context.Context) {
+	s.loop(ctx)
+	s.log.Info("monitor service stopped")
+}
+
+// sleep waits for the delay, it returns false if the ctx is done first
+func sleep(ctx context.Context, delay time.Duration) bool {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
 }
 
 // loop keeps trying to connect to the monitor server, reporting chain events
 // until termination.
-func (s *Service) loop() {
+func (s *Service) loop(ctx context.Context) {
 	// Loop reporting until termination
-	for {
+	for ctx.Err() == nil {
 		info, err := s.rpc.NodeInfo()
+		s.rpcResult(err)
 		if err != nil {
-			logs.Error("rpc getNodeInfo error %v", err)
-			time.Sleep(5 * time.Second)
+			delay := s.nextDelay(s.rpcBackoff, backoffRPC)
+			s.log.Error("rpc getNodeInfo error(retry after %v, attempt %v) %v", delay, s.rpcBackoff.Attempt(), err)
+			if !sleep(ctx, delay) {
+				return
+			}
 			continue
 		}
-		shard := info.Shard
-		websocketURL, _ := config.ShardMap[fmt.Sprintf("%v", shard)]
-
-		wsPath := fmt.Sprintf("%s%s", websocketURL, s.wsRouter)
-		s.wsPath = wsPath
-		logs.Debug("now shard %v, wsPath %v", shard, wsPath)
-
-		// Resolve the URL, defaulting to TLS, but falling back to none too
-		path := fmt.Sprintf("%s", s.wsPath)
-		urls := []string{path}
-		if !strings.Contains(path, "://") {
-			urls = []string{"wss://" + path, "ws://" + path}
-			urls = append(urls, "https://"+path, "http://"+path)
-		}
+		// the node answered, the earlier rpc failures do not delay the next retries
+		s.resetDelay(s.rpcBackoff, backoffRPC)
+		shard := s.nodeShard(info)
 
-		// Establish a web socket connection to the server on any supported URL
-		var (
-			conf *websocket.Config
-			conn *websocket.Conn
-		)
-		for _, url := range urls {
-			if conf, err = websocket.NewConfig(url, "http://localhost/"); err != nil {
-				continue
-			}
-			conf.Dialer = &net.Dialer{Timeout: 5 * time.Second}
-			if conn, err = websocket.DialConfig(conf); err == nil {
-				break
-			}
-		}
+		// Establish a web socket connection to the first reachable monitor server of the shard
+		conn, server, err := s.dial(shard)
 		if err != nil {
-			logs.Warn("Stats server unreachable(resend after %v), err %v", s.delayReConnTime, err)
-			time.Sleep(s.delayReConnTime)
+			delay := s.nextDelay(s.dialBackoff, backoffDial)
+			s.log.Warn("Stats server unreachable(reconnect after %v, attempt %v), err %v", delay, s.dialBackoff.Attempt(), err)
+			s.setConnected(false, err)
+			s.journalOffline()
+			if !sleep(ctx, delay) {
+				return
+			}
 			continue
 		}
 
+		if s.connected {
+			wsReconnects.Inc(s.name)
+		}
+		s.connected = true
+		s.setServer(server)
+		s.wsPath = server.URL + s.wsRouter
+		s.log.Debug("now shard %v, wsPath %v", shard, s.wsPath)
+
 		go s.readLoop(conn)
 
-		// first get the node base and append s.node
-		coinBase, err := s.getCoinBase(conn)
-		if err != nil {
-			logs.Warn("Initial get coinBase failed(reconnect after %v), err %v", s.delaySendTime, err)
+		//Send the initial stats so our node looks decent from the get go
+		if err = s.reportAllNodeInfo(conn); err != nil {
+			delay := s.nextDelay(s.rpcBackoff, backoffRPC)
+			s.log.Warn("Initial stats report failed(reconnect after %v, attempt %v), err %v", delay, s.rpcBackoff.Attempt(), err)
+			s.setConnected(false, err)
 			if conn != nil {
 				conn.Close()
 			}
-			time.Sleep(s.delaySendTime)
+			if !sleep(ctx, delay) {
+				return
+			}
 			continue
 		}
-		s.node = s.hostname + "_" + coinBase
 
-		//Send the initial stats so our node looks decent from the get go
-		if err = s.reportAllNodeInfo(conn); err != nil {
-			logs.Warn("Initial stats report failed(reconnect after %v), err %v", s.delaySendTime, err)
-			if conn != nil {
-				conn.Close()
+		// Replay what happened while we were away, right after the hello
+		if err = s.replayJournal(conn); err != nil {
+			delay := s.nextDelay(s.rpcBackoff, backoffRPC)
+			s.log.Warn("Journal replay failed(reconnect after %v, attempt %v), err %v", delay, s.rpcBackoff.Attempt(), err)
+			s.setConnected(false, err)
+			conn.Close()
+			if !sleep(ctx, delay) {
+				return
 			}
-			time.Sleep(s.delaySendTime)
 			continue
 		}
 
-		fullReport := time.NewTicker(s.fullEventTickerTime)
+		s.setConnected(true, nil)
+		s.reported()
+		connectedAt := time.Now()
+
+		fullInterval, blockInterval := s.intervals()
+		fullReport := time.NewTicker(fullInterval)
 
-		blockReport := time.NewTicker(s.latestBlockEventTickerTime)
+		blockReport := time.NewTicker(blockInterval)
 
-		for err == nil {
+		stopped := false
+		for err == nil && !stopped {
 			select {
+			case <-ctx.Done():
+				stopped = true
+
 			case <-fullReport.C:
 				if err = s.report(conn); err != nil {
-					logs.Warn("Full stats report failed", "err", err)
+					s.log.Warn("Full stats report failed", "err", err)
+				} else {
+					s.reported()
+				}
+
+			case <-s.reportCh:
+				if err = s.report(conn); err != nil {
+					s.log.Warn("Requested stats report failed", "err", err)
+				} else {
+					s.reported()
 				}
 
+			case <-s.reloadCh:
+				fullInterval, blockInterval = s.intervals()
+				fullReport.Reset(fullInterval)
+				blockReport.Reset(blockInterval)
+				err = s.checkServers(shard)
+
 			case <-blockReport.C:
 				if err = s.reportCurrentBlock(conn); err != nil {
-					logs.Warn("Current block report failed", "err", err)
+					s.log.Warn("Current block report failed", "err", err)
+				} else {
+					s.reported()
 				}
 			}
 		}
+		fullReport.Stop()
+		blockReport.Stop()
+		if err == errServersChanged {
+			// the reloaded monitor servers of the shard are dialed by priority again
+			s.log.Warn("%v, reconnect to the monitor servers of shard %v", err, shard)
+			if err = s.emitLast(conn, protocol.EventGoodbye, &protocol.Goodbye{Header: s.header(), Reason: "reload"}); err != nil {
+				s.log.Warn("goodbye report failed, err %v", err)
+			}
+			s.setConnected(false, nil)
+			conn.Close()
+			continue
+		}
+		var change *shardChange
+		if errors.As(err, &change) {
+			// leave the monitor server of the old shard and connect to the new one now
+			s.log.Warn("%v, reconnect to the monitor server of shard %v", change, change.to)
+			s.leaveShard(conn, change)
+			s.setConnected(false, nil)
+			conn.Close()
+			continue
+		}
+		if stopped {
+			// tell the monitor server the node goes offline before leaving
+			if err = s.reportOffline(conn); err != nil {
+				s.log.Warn("Offline stats report failed, err %v", err)
+			}
+			s.setConnected(false, nil)
+			conn.Close()
+			return
+		}
 		// Make sure the connection is closed
+		s.setConnected(false, err)
 		conn.Close()
+
+		// a stable connection starts the delays again, a flapping one keeps growing them
+		if time.Since(connectedAt) >= s.backoffResetAfter {
+			s.resetDelay(s.dialBackoff, backoffDial)
+			s.resetDelay(s.rpcBackoff, backoffRPC)
+		}
+		delay := s.nextDelay(s.dialBackoff, backoffDial)
+		s.log.Warn("Stats server connection lost(reconnect after %v, attempt %v), err %v", delay, s.dialBackoff.Attempt(), err)
+		if !sleep(ctx, delay) {
+			return
+		}
 	}
 }
 
 // readLoop loops as long as the connection is alive and retrieves data packets
-// from the network socket. If any of them match an active request, it forwards
-// it, if they themselves are requests it initiates a reply, and lastly it drops
-// unknown packets.
+// from the network socket. The commands of the monitor server are dispatched
+// to their handlers, the invalid and unknown ones are answered with an error
+// emit. Only a read error closes the connection.
 func (s *Service) readLoop(conn *websocket.Conn) {
 	// If the read loop exists, close the connection
 	defer conn.Close()
 
 	for {
 		// Retrieve the next generic network packet and bail out on error
-		var msg map[string][]interface{}
-		if err := websocket.JSON.Receive(conn, &msg); err != nil {
-			logs.Warn("Failed to decode stats server message", "err", err)
-			return
-		}
-		logs.Debug("Received message from stats server", "msg", msg)
-		if len(msg["emit"]) == 0 {
-			logs.Warn("Stats server sent non-broadcast", "msg", msg)
+		var data []byte
+		if err := websocket.Message.Receive(conn, &data); err != nil {
+			s.log.Warn("Failed to read stats server message, err %v", err)
 			return
 		}
-		command, ok := msg["emit"][0].(string)
-		if !ok {
-			logs.Warn("Invalid stats server message type", "type", msg["emit"][0])
-			return
-		}
-		// If the message is a ping reply, deliver (someone must be listening!)
-		if len(msg["emit"]) == 2 && command == "node-pong" {
-			select {
-			case s.pongCh <- struct{}{}:
-				// Pong delivered, continue listening
-				continue
-			default:
-				// Ping routine dead, abort
-				logs.Warn("Stats server pinger seems to have died")
-				return
-			}
+		msg, err := protocol.Parse(data)
+		if err != nil {
+			s.commandFailed(conn, "", err)
+			continue
 		}
-		// Report anything else and continue
-		logs.Info("stats message", "msg", msg)
-	}
-}
-
-// nodeInfo is the collection of metainformation about a node that is displayed
-// on the monitoring page.
-type nodeInfo struct {
-	Name        string `json:"name"`
-	Node        string `json:"node"`
-	Port        int    `json:"port"` // the monitor api client port, can overwrite use monitor api client api port
-	Protocol    string `json:"protocol"`
-	API         string `json:"api"`
-	Os          string `json:"os"`
-	OsVer       string `json:"os_v"`
-	Client      string `json:"client"`
-	NodeVersion string `json:"nodeVersion"` // the monitor api client version
-	NetVersion  string `json:"netVersion"`
-	Shard       uint   `json:"shard"`
-}
-
-type nodeInfo1 struct {
-	Name        string `json:"name"`
-	Node        string `json:"node"`
-	Port        int    `json:"port"` // the monitor api client port, can overwrite use monitor api client api port
-	Protocol    string `json:"protocol"`
-	API         string `json:"api"`
-	Os          string `json:"os"`
-	OsVer       string `json:"os_v"`
-	Client      string `json:"client"`
-	NodeVersion string `json:"nodeVersion"` // the monitor api client version
-	NetVersion  uint64 `json:"netVersion"`
-	Shard       uint   `json:"shard"`
-}
-
-// nodeStats is the information about the local node.
-type nodeStats struct {
-	Active   bool `json:"active"`
-	Syncing  bool `json:"syncing"`
-	Mining   bool `json:"mining"`
-	Hashrate int  `json:"hashrate"`
-	Peers    int  `json:"peers"`
-	GasPrice int  `json:"gasPrice"`
-	Uptime   int  `json:"uptime"`
-}
-
-type apiCurrentBlock struct {
-	HeadHash   string   `json:"headHash"`
-	Height     uint64   `json:"height"`
-	Timestamp  *big.Int `json:"timestamp"`
-	Difficulty *big.Int `json:"difficulty"`
-	Creater    string   `json:"miner"`
-	TxCount    int      `json:"txcount"`
+		s.log.Debug("Received message from stats server, event %v, payload %s", msg.Event, msg.Payload)
+		s.dispatch(conn, msg)
+	}
+}
+
+// header returns the header of the payloads of the node
+func (s *Service) header() protocol.Header {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	return protocol.NewHeader(s.node, s.currentNetVersion, s.shard)
+}
+
+// setHeader changes the node id, the net version and the shard of the emits.
+// They are written by the loop only, which reads them without the lock, the
+// command and history goroutines read them through header.
+func (s *Service) setHeader(node string, netVersion uint64, shard uint) {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	s.node = node
+	s.currentNetVersion = netVersion
+	s.shard = shard
+}
+
+// protocolStats converts the stats of the rpc to the stats of the emits
+func protocolStats(stats *rpc.NodeStats) *protocol.NodeStats {
+	return &protocol.NodeStats{
+		Active:   stats.Active,
+		Syncing:  stats.Syncing,
+		Mining:   stats.Mining,
+		Hashrate: stats.Hashrate,
+		Peers:    stats.Peers,
+	}
+}
+
+// protocolBlock converts the block of the rpc to the block of the emits
+func protocolBlock(block *rpc.CurrentBlock) *protocol.Block {
+	return &protocol.Block{
+		HeadHash:   block.HeadHash,
+		Height:     block.Height,
+		Timestamp:  block.Timestamp,
+		Difficulty: block.Difficulty,
+		Miner:      block.Creator,
+		TxCount:    block.TxCount,
+	}
 }
 
 // report collects all possible data to report and send it to the stats server.
@@ -321,11 +506,19 @@ func (s *Service) report(conn *websocket.Conn) error {
 		return err
 	}
 
-	if err := s.reportNodeStats(conn); err != nil {
+	// the node info, the stats and the current block are fetched in one batch request
+	full, err := s.getFullReport(conn)
+	if err != nil {
+		return err
+	}
+	if err = s.checkShard(full.NodeInfo); err != nil {
+		return err
+	}
+	if err = s.emit(conn, protocol.EventStats, s.nodeStatsInfo(full.NodeStats)); err != nil {
 		return err
 	}
 
-	return nil
+	return s.sendBlockInfo(conn, s.blockInfo(full.CurrentBlock))
 }
 
 // reportLatency sends a ping request to the server, measures the RTT time and
@@ -337,19 +530,12 @@ func (s *Service) reportLatency(conn *websocket.Conn) error {
 		return errors.New(err.Error())
 	}
 
-	report := map[string][]interface{}{
-		"emit": {"latency", map[string]interface{}{
-			"id":         s.node,
-			"latency":    latency,
-			"netVersion": s.currentNetVersion,
-			"shard":      s.shard,
-		}},
-	}
 	// Send back the measured latency
-	logs.Debug("Sending measured latency to seele monitor", "latency", latency)
-	jsonReport, _ := json.Marshal(report)
-	logs.Debug("Sending node latency to monitor\n %v", string(jsonReport))
-	return websocket.JSON.Send(conn, report)
+	s.log.Debug("Sending measured latency to seele monitor", "latency", latency)
+	return s.emit(conn, protocol.EventLatency, &protocol.LatencyReport{
+		Header:  s.header(),
+		Latency: latency,
+	})
 }
 
 // reportNodeInfo retrieves various stats about the node at the networking and
@@ -360,12 +546,10 @@ func (s *Service) reportNodeInfo(conn *websocket.Conn) error {
 	if err != nil {
 		return errors.New(err.Error())
 	}
-	report := map[string][]interface{}{
-		"emit": {"nodeInfo", nodeInfo},
-	}
-	jsonReport, _ := json.Marshal(report)
-	logs.Debug("Sending node info to monitor\n %v", string(jsonReport))
-	return websocket.JSON.Send(conn, report)
+	return s.emit(conn, protocol.EventNodeInfo, &protocol.NodeInfoReport{
+		Header: s.header(),
+		Info:   nodeInfo,
+	})
 }
 
 // reportNodeStats retrieves various stats about the node at the networking and
@@ -373,36 +557,31 @@ func (s *Service) reportNodeInfo(conn *websocket.Conn) error {
 func (s *Service) reportNodeStats(conn *websocket.Conn) error {
 	nodeStats, err := s.getNodeStats(conn)
 	if err != nil {
-		logs.Error("rpc reportNodeStats error %v", err)
+		s.log.Error("rpc reportNodeStats error %v", err)
 		return err
 	}
-	report := map[string][]interface{}{
-		"emit": {"stats", nodeStats},
-	}
-	jsonReport, _ := json.Marshal(report)
-	logs.Debug("Sending node stats to monitor\n %v", string(jsonReport))
-	return websocket.JSON.Send(conn, report)
+	return s.emit(conn, protocol.EventStats, nodeStats)
 }
 
 func (s *Service) getLatency(conn *websocket.Conn) (string, error) {
 	// Send the current time to the monitor server
 	start := time.Now()
 
-	ping := map[string][]interface{}{
-		"emit": {"node-ping", map[string]interface{}{
-			"id":         s.node,
-			"clientTime": start.UnixNano() / 1000000,
-			"netVersion": s.currentNetVersion,
-			"shard":      s.shard,
-		}},
+	ping, err := protocol.NewMessage(protocol.EventNodePing, &protocol.Ping{
+		Header:     s.header(),
+		ClientTime: start.UnixNano() / 1000000,
+	})
+	if err != nil {
+		return "-1", err
 	}
-	jsonReport, _ := json.Marshal(ping)
-	logs.Debug("Sending node ping to monitor\n %v", string(jsonReport))
+	s.log.Debug("Sending node ping to monitor\n %s", ping.Payload)
 	if err := websocket.JSON.Send(conn, ping); err != nil {
-		logs.Error("rpc reportLatency error %v", err)
+		s.log.Error("rpc reportLatency error %v", err)
 		return "-1", err
 
 	}
+	s.countEmit(ping)
+	s.publishEmit(ping)
 
 	// Wait for the pong request to arrive back
 	select {
@@ -414,28 +593,36 @@ func (s *Service) getLatency(conn *websocket.Conn) (string, error) {
 	}
 	latencyFloat := float32(int((time.Since(start)/time.Duration(2)).Nanoseconds()*10)) / 10000000
 	latency := fmt.Sprintf("%.1f", latencyFloat)
-	logs.Debug("latency is %vms", latency)
+	s.log.Debug("latency is %vms", latency)
+	s.updateLatencyMetrics(latency)
 	return latency, nil
 }
 
-func (s *Service) getNodeInfo(conn *websocket.Conn) (map[string]interface{}, error) {
+func (s *Service) getNodeInfo(conn *websocket.Conn) (*protocol.NodeInfo, error) {
 	info, err := s.rpc.NodeInfo()
+	s.rpcResult(err)
 	if err != nil {
-		logs.Error("rpc getNodeInfo error %v", err)
+		s.log.Error("rpc getNodeInfo error %v", err)
 		s.detectErrorAndReport(conn)
 		return nil, err
 	}
+	return s.nodeInfo(info)
+}
+
+// nodeInfo is the node info of the hello, it updates the net version and the
+// shard of the header
+func (s *Service) nodeInfo(info *rpc.NodeInfo) (*protocol.NodeInfo, error) {
+	s.cache.setInfo(info)
 
 	// update netVersion
 	version, err := strconv.ParseFloat(info.NetVersion, 10)
 	if err != nil {
-		logs.Warn("netversion err %s", err.Error())
+		s.log.Warn("netversion err %s", err.Error())
 		return nil, err
 	}
-	s.currentNetVersion = uint64(version)
-	s.shard = info.Shard
+	s.setHeader(s.node, uint64(version), s.nodeShard(info))
 
-	nodeInfoData := nodeInfo1{
+	return &protocol.NodeInfo{
 		Name:        config.APPName,
 		NodeVersion: config.VERSION,
 		Node:        info.Node,
@@ -447,31 +634,42 @@ func (s *Service) getNodeInfo(conn *websocket.Conn) (map[string]interface{}, err
 		API:         info.Protocol,
 		NetVersion:  uint64(version),
 		Shard:       s.shard,
-	}
-
-	nodeInfo := map[string]interface{}{
-		"id":   s.node,
-		"info": nodeInfoData,
-	}
-
-	return nodeInfo, nil
+		History:     true,
+	}, nil
 }
 
-func (s *Service) getNodeStats(conn *websocket.Conn) (map[string]interface{}, error) {
+func (s *Service) getNodeStats(conn *websocket.Conn) (*protocol.StatsReport, error) {
 	stats, err := s.rpc.NodeStats()
+	s.rpcResult(err)
 	if err != nil {
-		logs.Error("rpc getNodeStats error %v", err)
+		s.log.Error("rpc getNodeStats error %v", err)
 		s.detectErrorAndReport(conn)
 		return nil, err
 	}
-	nodeStats := map[string]interface{}{
-		"id":         s.node,
-		"stats":      stats,
-		"netVersion": s.currentNetVersion,
-		"shard":      s.shard,
+	return s.nodeStatsInfo(stats), nil
+}
+
+// nodeStatsInfo is the payload of the stats emit
+func (s *Service) nodeStatsInfo(stats *rpc.NodeStats) *protocol.StatsReport {
+	s.updateStatsMetrics(stats)
+	s.cache.setStats(stats)
+	return &protocol.StatsReport{
+		Header: s.header(),
+		Stats:  protocolStats(stats),
 	}
+}
 
-	return nodeStats, nil
+// getFullReport fetches the node stats, the node info and the current block
+// in one batch request
+func (s *Service) getFullReport(conn *websocket.Conn) (*rpc.FullReport, error) {
+	full, err := s.rpc.FullReport()
+	s.rpcResult(err)
+	if err != nil {
+		s.log.Error("rpc getFullReport error %v", err)
+		s.detectErrorAndReport(conn)
+		return nil, err
+	}
+	return full, nil
 }
 
 func (s *Service) reportCurrentBlock(conn *websocket.Conn) error {
@@ -481,29 +679,27 @@ func (s *Service) reportCurrentBlock(conn *websocket.Conn) error {
 	return nil
 }
 
-func (s *Service) getCurrentBlockInfo(conn *websocket.Conn) (map[string]interface{}, error) {
+func (s *Service) getCurrentBlockInfo(conn *websocket.Conn) (*protocol.BlockReport, error) {
 	block, err := s.rpc.CurrentBlock(-1, true)
+	s.rpcResult(err)
 	if err != nil {
-		logs.Error("rpc getCurrentBlockInfo error %v", err)
+		s.log.Error("rpc getCurrentBlockInfo error %v", err)
 		s.detectErrorAndReport(conn)
 		return nil, err
 	}
+	return s.blockInfo(block), nil
+}
 
-	blockInfo := map[string]interface{}{
-		"id": s.node,
-		"block": &apiCurrentBlock{
-			HeadHash:   block.HeadHash,
-			Height:     block.Height,
-			Timestamp:  block.Timestamp,
-			Difficulty: block.Difficulty,
-			Creater:    block.Creator,
-			TxCount:    block.TxCount,
-		},
-		"netVersion": s.currentNetVersion,
-		"shard":      s.shard,
-	}
+// blockInfo is the payload of the block emit, the block becomes the current block
+func (s *Service) blockInfo(block *rpc.CurrentBlock) *protocol.BlockReport {
 	s.currentBlockHeight = block.Height
-	return blockInfo, nil
+	s.currentBlock = block
+	s.updateBlockMetrics(block)
+	s.cache.setBlock(block)
+	return &protocol.BlockReport{
+		Header: s.header(),
+		Block:  protocolBlock(block),
+	}
 }
 
 // reportCurrentBlockInfo retrieves various stats about the node at the networking and
@@ -511,75 +707,97 @@ func (s *Service) getCurrentBlockInfo(conn *websocket.Conn) (map[string]interfac
 func (s *Service) reportCurrentBlockInfo(conn *websocket.Conn) error {
 	blockInfo, err := s.getCurrentBlockInfo(conn)
 	if err != nil {
-		logs.Error("rpc reportCurrentBlockInfo error %v", err)
+		s.log.Error("rpc reportCurrentBlockInfo error %v", err)
+		return err
+	}
+	return s.sendBlockInfo(conn, blockInfo)
+}
+
+// sendBlockInfo sends the current block if it is new or the chain reorganized
+func (s *Service) sendBlockInfo(conn *websocket.Conn, blockInfo *protocol.BlockReport) error {
+	reorged, err := s.detectReorg(conn, s.currentBlock)
+	if err != nil {
+		s.log.Error("rpc reportCurrentBlockInfo reorg error %v", err)
 		return err
 	}
 
-	// if current block height gt the prev block height send the block info
-	if s.currentBlockHeight > s.latestBlockHeight {
+	// if current block height gt the prev block height or the chain reorganized send the block info
+	if reorged || s.currentBlockHeight > s.latestBlockHeight {
 		s.latestBlockHeight = s.currentBlockHeight
-		report := map[string][]interface{}{
-			"emit": {"block", blockInfo},
-		}
-		jsonReport, _ := json.Marshal(report)
-		logs.Debug("Sending node current block to monitor\n %v", string(jsonReport))
-		return websocket.JSON.Send(conn, report)
+		return s.emit(conn, protocol.EventBlock, blockInfo)
 	} else {
-		logs.Debug("no Sending node current block to monitor, currentBlockHeight: %v, latestBlockHeight: %v", s.currentBlockHeight, s.latestBlockHeight)
+		s.log.Debug("no Sending node current block to monitor, currentBlockHeight: %v, latestBlockHeight: %v", s.currentBlockHeight, s.latestBlockHeight)
 	}
 	return nil
 }
 
 // reportAllNodeInfo send this info to monitor, the first start conn or reconnect
 func (s *Service) reportAllNodeInfo(conn *websocket.Conn) error {
-	// nodeInfo must come first
-	info, err := s.getNodeInfo(conn)
+	// the node info, the coinbase, the block and the stats are fetched in one batch request
+	full, err := s.getFullReport(conn)
 	if err != nil {
-		logs.Error("reportAllNodeInfo %v", err)
+		s.log.Error("reportAllNodeInfo %v", err)
 		return err
 	}
-
-	block, err := s.getCurrentBlockInfo(conn)
+	// the node info comes first, it sets the net version and the shard of the header
+	info, err := s.nodeInfo(full.NodeInfo)
 	if err != nil {
-		logs.Error("reportAllNodeInfo %v", err)
+		s.log.Error("reportAllNodeInfo %v", err)
 		return err
 	}
-
-	stats, err := s.getNodeStats(conn)
+	coinBase, err := coinBaseOf(full.Info)
 	if err != nil {
-		logs.Error("reportAllNodeInfo %v", err)
+		s.log.Error("reportAllNodeInfo %v", err)
 		return err
 	}
+	s.setHeader(s.hostname+"_"+coinBase, s.currentNetVersion, s.shard)
+	block := s.blockInfo(full.CurrentBlock)
+	stats := s.nodeStatsInfo(full.NodeStats)
+
 	latency, err := s.getLatency(conn)
 	if err != nil {
-		logs.Error("reportAllNodeInfo %v", err)
+		s.log.Error("reportAllNodeInfo %v", err)
 		return err
 	}
 
-	allNodeInfo := map[string]interface{}{
-		"id":         s.node,
-		"info":       info["info"],
-		"block":      block["block"],
-		"stats":      stats["stats"],
-		"latency":    latency,
-		"netVersion": s.currentNetVersion,
-		"shard":      s.shard,
+	hello := &protocol.Hello{
+		Header:  s.header(),
+		Info:    info,
+		Block:   block.Block,
+		Stats:   stats.Stats,
+		Latency: latency,
+	}
+	// sign the hello, the monitor server verifies it with the same WsPass
+	if pass := s.serverPass(); pass != "" {
+		hello.Timestamp = time.Now().UnixNano() / int64(time.Millisecond)
+		hello.Secret = utils.SignSecret(pass, s.node, hello.Timestamp)
 	}
-	report := map[string][]interface{}{
-		"emit": {"hello", allNodeInfo},
+	report, err := protocol.NewMessage(protocol.EventHello, hello)
+	if err != nil {
+		return err
+	}
+	s.log.Debug("Sending node all info to monitor\n %s", report.Payload)
+	if err = websocket.JSON.Send(conn, report); err != nil {
+		return err
 	}
-	jsonReport, _ := json.Marshal(report)
-	logs.Debug("Sending node all info to monitor\n %v", string(jsonReport))
-	return websocket.JSON.Send(conn, report)
+	s.countEmit(report)
+	s.publishEmit(report)
+	return nil
 }
 
 func (s *Service) getCoinBase(conn *websocket.Conn) (string, error) {
 	info, err := s.rpc.GetInfo()
+	s.rpcResult(err)
 	if err != nil {
-		logs.Error("rpc getCoinBase error %v", err)
+		s.log.Error("rpc getCoinBase error %v", err)
 		s.detectErrorAndReport(conn)
 		return "", err
 	}
+	return coinBaseOf(info)
+}
+
+// coinBaseOf returns the coinbase in the result of seele_getInfo
+func coinBaseOf(info map[string]interface{}) (string, error) {
 	coinBase := info["Coinbase"]
 	v, ok := coinBase.(string)
 	if !ok {
@@ -592,30 +810,110 @@ func (s *Service) getCoinBase(conn *websocket.Conn) (string, error) {
 // detectErrorAndReport detect the error and report to monitor
 func (s *Service) detectErrorAndReport(conn *websocket.Conn) error {
 	s.currentErrorTimes++
-	if s.currentErrorTimes >= s.reportErrorAfterTimes {
-		logs.Error("conn error occur times: %v >= %v, will report error", s.currentErrorTimes, s.reportErrorAfterTimes)
+	reportErrorAfterTimes := s.errorReportTimes()
+	if s.currentErrorTimes >= reportErrorAfterTimes {
+		s.log.Error("conn error occur times: %v >= %v, will report error", s.currentErrorTimes, reportErrorAfterTimes)
 		s.currentErrorTimes = 0
 		return s.reportServerError(conn)
 	}
-	logs.Debug("conn error occur times: %v < %v", s.currentErrorTimes, s.reportErrorAfterTimes)
+	s.log.Debug("conn error occur times: %v < %v", s.currentErrorTimes, reportErrorAfterTimes)
 	return nil
 }
 
 // reportServerError report the error to monitor server
 func (s *Service) reportServerError(conn *websocket.Conn) error {
-	nodeStats := map[string]interface{}{
-		"id": s.node,
-		"stats": map[string]interface{}{
-			"active":  false,
-			"syncing": false,
-		},
-		"netVersion": s.currentNetVersion,
-		"shard":      s.shard,
-	}
-	report := map[string][]interface{}{
-		"emit": {"stats", nodeStats},
-	}
-	jsonReport, _ := json.Marshal(report)
-	logs.Debug("Sending node error info to monitor\n %v", string(jsonReport))
-	return websocket.JSON.Send(conn, report)
+	s.log.Debug("Sending node error info to monitor")
+	return s.emit(conn, protocol.EventStats, &protocol.StatsReport{
+		Header: s.header(),
+		Stats:  &protocol.NodeStats{Active: false, Syncing: false},
+	})
+}
+
+// reportOffline sends the stats of the stopped node, it is not journaled
+func (s *Service) reportOffline(conn *websocket.Conn) error {
+	return s.emitLast(conn, protocol.EventStats, &protocol.StatsReport{
+		Header: s.header(),
+		Stats:  &protocol.NodeStats{Active: false, Syncing: false},
+	})
+}
+
+// emitLast sends an emit before closing the connection within 5 seconds, it
+// is not journaled
+func (s *Service) emitLast(conn *websocket.Conn, event string, payload interface{}) error {
+	report, err := protocol.NewMessage(event, payload)
+	if err != nil {
+		return err
+	}
+	s.log.Debug("Sending node %v to monitor\n %s", event, report.Payload)
+	conn.SetWriteDeadline(time.Now().Add(5 * time.Second))
+	if err = websocket.JSON.Send(conn, report); err != nil {
+		return err
+	}
+	s.countEmit(report)
+	s.publishEmit(report)
+	return nil
+}
+
+// emit encodes the payload of the event and sends it to the monitor server
+func (s *Service) emit(conn *websocket.Conn, event string, payload interface{}) error {
+	report, err := protocol.NewMessage(event, payload)
+	if err != nil {
+		return err
+	}
+	s.log.Debug("Sending node %v to monitor\n %s", event, report.Payload)
+	return s.send(conn, report)
+}
+
+// send sends the report to the monitor server. If there is no connection or
+// the send fails, the report goes into the journal to be replayed later.
+func (s *Service) send(conn *websocket.Conn, report *protocol.Message) error {
+	s.publishEmit(report)
+
+	var err error
+	if conn != nil {
+		if err = websocket.JSON.Send(conn, report); err == nil {
+			s.countEmit(report)
+			return nil
+		}
+	} else {
+		err = errors.New("monitor server not connected")
+	}
+
+	if s.journal != nil {
+		if journalErr := s.journal.append(report); journalErr != nil {
+			s.log.Warn("journal emit failed, err %v", journalErr)
+		}
+	}
+	return err
+}
+
+// journalOffline samples the node while the monitor server is unreachable,
+// the emits are recorded into the journal.
+func (s *Service) journalOffline() {
+	if s.journal == nil {
+		return
+	}
+	if s.node == s.hostname {
+		coinBase, err := s.getCoinBase(nil)
+		if err != nil {
+			return
+		}
+		s.setHeader(s.hostname+"_"+coinBase, s.currentNetVersion, s.shard)
+	}
+
+	s.reportNodeStats(nil)
+	s.reportCurrentBlockInfo(nil)
+	s.log.Debug("journal has %v emits waiting for replay", s.journal.len())
+}
+
+// replayJournal sends the journaled emits in order after the hello
+func (s *Service) replayJournal(conn *websocket.Conn) error {
+	if s.journal == nil || s.journal.len() == 0 {
+		return nil
+	}
+	sent, err := s.journal.replay(func(raw json.RawMessage) error {
+		return websocket.Message.Send(conn, string(raw))
+	})
+	s.log.Info("replayed %v journaled emits to monitor, %v left", sent, s.journal.len())
+	return err
 }
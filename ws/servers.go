@@ -0,0 +1,101 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/seeleteam/monitor-api/config"
+)
+
+// serverURLs returns the urls to dial the monitor server, defaulting to TLS,
+// but falling back to none too
+func (s *Service) serverURLs(server *config.MonitorServer) []string {
+	path := server.URL + s.wsRouter
+	if strings.Contains(path, "://") {
+		return []string{path}
+	}
+	return []string{"wss://" + path, "ws://" + path, "https://" + path, "http://" + path}
+}
+
+// dial connects to the monitor servers of the shard by priority and returns
+// the first reachable one, the next servers are the failovers of the first.
+func (s *Service) dial(shard uint) (*websocket.Conn, *config.MonitorServer, error) {
+	servers := config.MonitorServers(fmt.Sprintf("%v", shard))
+	if len(servers) == 0 {
+		s.log.Error("shard config error, shard %v exist error web socket url", shard)
+		return nil, nil, fmt.Errorf("no web socket url for shard %v", shard)
+	}
+
+	s.shardServers = servers
+	err := errors.New("no monitor server dialed")
+	for i := range servers {
+		server := &servers[i]
+		var tlsConfig *tls.Config
+		if server.TLS != nil {
+			if tlsConfig, err = server.TLS.Config(); err != nil {
+				s.log.Warn("monitor server %v tls config error %v", server.URL, err)
+				continue
+			}
+		}
+		for _, url := range s.serverURLs(server) {
+			var conf *websocket.Config
+			if conf, err = websocket.NewConfig(url, "http://localhost/"); err != nil {
+				continue
+			}
+			conf.Dialer = &net.Dialer{Timeout: 5 * time.Second}
+			conf.TlsConfig = tlsConfig
+			var conn *websocket.Conn
+			if conn, err = websocket.DialConfig(conf); err == nil {
+				return conn, server, nil
+			}
+		}
+		if i < len(servers)-1 {
+			s.log.Warn("monitor server %v of shard %v unreachable, fail over to %v, err %v", server.URL, shard, servers[i+1].URL, err)
+		}
+	}
+	return nil, nil, err
+}
+
+// errServersChanged ends the connection when a reload changed the monitor
+// servers of the shard
+var errServersChanged = errors.New("monitor servers changed")
+
+// checkServers returns errServersChanged if the monitor servers of the shard
+// are not the ones dialed
+func (s *Service) checkServers(shard uint) error {
+	if !reflect.DeepEqual(s.shardServers, config.MonitorServers(fmt.Sprintf("%v", shard))) {
+		return errServersChanged
+	}
+	return nil
+}
+
+// setServer records the monitor server of the connection
+func (s *Service) setServer(server *config.MonitorServer) {
+	s.statusMu.Lock()
+	s.server = server
+	s.status.MonitorServer = server.URL
+	s.statusMu.Unlock()
+}
+
+// serverPass returns the password signing the hello, the one of the monitor
+// server if set, otherwise WsPass
+func (s *Service) serverPass() string {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	if s.server != nil && s.server.Pass != "" {
+		return s.server.Pass
+	}
+	return s.pass
+}
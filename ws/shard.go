@@ -0,0 +1,57 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"fmt"
+
+	"golang.org/x/net/websocket"
+
+	"github.com/seeleteam/monitor-api/protocol"
+	"github.com/seeleteam/monitor-api/rpc"
+)
+
+// shardChange is the error of the full report when the node moved to another shard
+type shardChange struct {
+	from, to uint
+}
+
+func (c *shardChange) Error() string {
+	return fmt.Sprintf("node moved from shard %v to %v", c.from, c.to)
+}
+
+// checkShard returns a shardChange if the node info is not in the shard
+// reported so far
+func (s *Service) checkShard(info *rpc.NodeInfo) error {
+	if info == nil {
+		return nil
+	}
+	if shard := s.nodeShard(info); shard != s.shard {
+		return &shardChange{from: s.shard, to: shard}
+	}
+	return nil
+}
+
+// leaveShard tells the monitor server of the old shard the node moved with a
+// shard-change and a goodbye, the next reports are in the new shard
+func (s *Service) leaveShard(conn *websocket.Conn, change *shardChange) {
+	err := s.emitLast(conn, protocol.EventShardChange, &protocol.ShardChange{
+		Header: s.header(),
+		From:   change.from,
+		To:     change.to,
+	})
+	if err == nil {
+		err = s.emitLast(conn, protocol.EventGoodbye, &protocol.Goodbye{
+			Header: s.header(),
+			Reason: protocol.EventShardChange,
+		})
+	}
+	if err != nil {
+		s.log.Warn("shard change report failed, err %v", err)
+	}
+	s.setHeader(s.node, s.currentNetVersion, change.to)
+	wsShardChanges.Inc(s.name)
+}
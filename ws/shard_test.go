@@ -0,0 +1,48 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/seeleteam/monitor-api/protocol"
+	"github.com/seeleteam/monitor-api/rpc"
+)
+
+func TestCheckShard(t *testing.T) {
+	s := &Service{shard: 1, shardOverride: -1}
+	assert.NoError(t, s.checkShard(nil))
+	assert.NoError(t, s.checkShard(&rpc.NodeInfo{Shard: 1}))
+
+	var change *shardChange
+	err := s.checkShard(&rpc.NodeInfo{Shard: 2})
+	assert.True(t, errors.As(err, &change))
+	assert.Equal(t, &shardChange{from: 1, to: 2}, change)
+	assert.EqualError(t, err, "node moved from shard 1 to 2")
+
+	// the shard override does not follow the node
+	s.shardOverride = 1
+	assert.NoError(t, s.checkShard(&rpc.NodeInfo{Shard: 2}))
+}
+
+func TestSetHeader(t *testing.T) {
+	s := &Service{node: "host", currentNetVersion: 1, shard: 1}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			// the commands read the header while the loop moves the node
+			header := s.header()
+			assert.True(t, header.Shard == 1 && header.ID == "host" || header.Shard == 2 && header.ID == "host_0x1")
+		}
+	}()
+	s.setHeader("host_0x1", 1, 2)
+	<-done
+	assert.Equal(t, protocol.NewHeader("host_0x1", 1, 2), s.header())
+}
@@ -0,0 +1,89 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"fmt"
+	"time"
+)
+
+// Status is the state a Service publishes for the readiness check
+type Status struct {
+	Name           string        `json:"name"`
+	ID             string        `json:"id"`
+	RPCEndpoint    string        `json:"rpcEndpoint"`
+	RPCReachable   bool          `json:"rpcReachable"`
+	Connected      bool          `json:"connected"`
+	MonitorServer  string        `json:"monitorServer,omitempty"` // url of the last monitor server connected
+	LastReport     time.Time     `json:"lastReport"`
+	LastError      string        `json:"lastError,omitempty"`
+	ReportInterval time.Duration `json:"-"` // full report interval, the last report is stale after some intervals
+}
+
+// Ready reports whether the node is reachable, the monitor server is
+// connected and the last report is within periods report intervals. If not
+// ready the reason is returned.
+func (st *Status) Ready(periods int, now time.Time) (bool, string) {
+	switch {
+	case !st.RPCReachable:
+		return false, "rpc unreachable"
+	case !st.Connected:
+		return false, "monitor server not connected"
+	case st.LastReport.IsZero():
+		return false, "no report yet"
+	}
+	if maxAge := time.Duration(periods) * st.ReportInterval; maxAge > 0 && now.Sub(st.LastReport) > maxAge {
+		return false, fmt.Sprintf("last report %v ago, over %v", now.Sub(st.LastReport).Truncate(time.Second), maxAge)
+	}
+	return true, ""
+}
+
+// Status returns a copy of the state of the service
+func (s *Service) Status() Status {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	status := s.status
+	status.Name = s.name
+	status.RPCEndpoint = s.rpc.CurrentEndpoint()
+	status.ReportInterval = s.fullEventTickerTime
+	return status
+}
+
+// rpcResult records whether the node answered the last rpc call
+func (s *Service) rpcResult(err error) {
+	s.statusMu.Lock()
+	s.status.RPCReachable = err == nil
+	if err != nil {
+		s.status.LastError = err.Error()
+	}
+	s.statusMu.Unlock()
+
+	if err != nil {
+		s.publishError(err)
+	}
+}
+
+// setConnected records whether the service is connected to the monitor server
+func (s *Service) setConnected(connected bool, err error) {
+	s.statusMu.Lock()
+	s.status.Connected = connected
+	if err != nil {
+		s.status.LastError = err.Error()
+	}
+	s.statusMu.Unlock()
+
+	if err != nil {
+		s.publishError(err)
+	}
+}
+
+// reported records a successful report to the monitor server
+func (s *Service) reported() {
+	s.statusMu.Lock()
+	defer s.statusMu.Unlock()
+	s.status.LastReport = time.Now()
+	s.status.ID = s.node
+}
@@ -0,0 +1,42 @@
+/**
+*  @file
+*  @copyright defined in monitor-api/LICENSE
+ */
+
+package ws
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusReady(t *testing.T) {
+	now := time.Now()
+	status := Status{
+		RPCReachable:   true,
+		Connected:      true,
+		LastReport:     now.Add(-25 * time.Second),
+		ReportInterval: 10 * time.Second,
+	}
+	ready, reason := status.Ready(3, now)
+	assert.True(t, ready)
+	assert.Empty(t, reason)
+
+	ready, reason = status.Ready(2, now)
+	assert.False(t, ready)
+	assert.Equal(t, "last report 25s ago, over 20s", reason)
+
+	status.Connected = false
+	ready, reason = status.Ready(3, now)
+	assert.False(t, ready)
+	assert.Equal(t, "monitor server not connected", reason)
+
+	status.RPCReachable = false
+	_, reason = status.Ready(3, now)
+	assert.Equal(t, "rpc unreachable", reason)
+
+	_, reason = (&Status{RPCReachable: true, Connected: true}).Ready(3, now)
+	assert.Equal(t, "no report yet", reason)
+}